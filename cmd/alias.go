@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"eve-profile-sync/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage labels for user and character IDs",
+	Long: `Manage labels for user and character IDs.
+
+Aliases are stored in config.yaml and shown next to the IDs in menus, the
+operation summary and every file listing, which helps telling apart accounts
+whose names cannot be looked up.`,
+}
+
+var aliasSetCmd = &cobra.Command{
+	Use:   "set <user|char> <id> <label>",
+	Short: "Set the label of a user or character ID",
+	Example: `  eve-profile-sync alias set user 12345678 "Main account"
+  eve-profile-sync alias set char 90000001 Hauler`,
+	Args: cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		label := strings.TrimSpace(strings.Join(args[2:], " "))
+		if label == "" {
+			fmt.Fprintf(os.Stderr, "Error: Label must not be empty\n")
+			os.Exit(1)
+		}
+
+		updateAliases(args[0], args[1], func(aliases map[string]string, id string) {
+			aliases[id] = label
+		})
+		fmt.Printf("Alias of %s ID %s set to %q\n", aliasKindName(args[0]), args[1], label)
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:     "remove <user|char> <id>",
+	Aliases: []string{"rm"},
+	Short:   "Remove the label of a user or character ID",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateAliases(args[0], args[1], func(aliases map[string]string, id string) {
+			if _, ok := aliases[id]; !ok {
+				fmt.Fprintf(os.Stderr, "Error: No alias registered for %s ID %s\n", aliasKindName(args[0]), id)
+				os.Exit(1)
+			}
+			delete(aliases, id)
+		})
+		fmt.Printf("Alias of %s ID %s removed\n", aliasKindName(args[0]), args[1])
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List registered aliases",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(cfg.UserAliases) == 0 && len(cfg.CharacterAliases) == 0 {
+			fmt.Println("No aliases registered")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tID\tLABEL")
+		for _, id := range sortedIDs(cfg.UserAliases) {
+			fmt.Fprintf(w, "user\t%s\t%s\n", id, cfg.UserAliases[id])
+		}
+		for _, id := range sortedIDs(cfg.CharacterAliases) {
+			fmt.Fprintf(w, "char\t%s\t%s\n", id, cfg.CharacterAliases[id])
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasSetCmd, aliasRemoveCmd, aliasListCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
+
+// updateAliases loads the config, applies change to the alias map of the
+// given kind and saves the config
+func updateAliases(kind, id string, change func(aliases map[string]string, id string)) {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid ID %q: must be a number\n", id)
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch kind {
+	case "user":
+		if cfg.UserAliases == nil {
+			cfg.UserAliases = make(map[string]string)
+		}
+		change(cfg.UserAliases, id)
+	case "char", "character":
+		if cfg.CharacterAliases == nil {
+			cfg.CharacterAliases = make(map[string]string)
+		}
+		change(cfg.CharacterAliases, id)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown kind %q: use user or char\n", kind)
+		os.Exit(1)
+	}
+
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to save config: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// aliasKindName returns the word used for a kind argument in messages
+func aliasKindName(kind string) string {
+	if kind == "user" {
+		return "user"
+	}
+	return "character"
+}
+
+// sortedIDs returns the keys of an alias map in numeric order
+func sortedIDs(aliases map[string]string) []string {
+	ids := make([]string, 0, len(aliases))
+	for id := range aliases {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
@@ -0,0 +1,681 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"eve-profile-sync/internal/backup"
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/profile"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage profile backups",
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old backups according to the retention policy",
+	Long: `Remove old backups according to the retention policy configured in
+config.yaml (backup_keep_last, backup_keep_days, backup_max_size). The flags
+override the configured values for this run. The newest backup of every
+profile is always kept.`,
+	Example: `  eve-profile-sync backup prune --dry-run
+  eve-profile-sync backup prune --keep-last 5 --max-size 200MB`,
+	Args: cobra.NoArgs,
+	Run:  runBackupPrune,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List backups and the operations that produced them",
+	Args:    cobra.NoArgs,
+	Run:     runBackupList,
+}
+
+var backupShowCmd = &cobra.Command{
+	Use:   "show <backup>",
+	Short: "Show the manifest and files of a backup",
+	Long: `Show the manifest of a backup: when and why it was taken, by which version,
+from which profile and server, and the size, modification time and SHA-256 of
+every archived file. The backup is given as a file name in the backup directory
+or as a path.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runBackupShow,
+}
+
+var backupExportCmd = &cobra.Command{
+	Use:   "export <snapshot>",
+	Short: "Export a snapshot as a zip backup",
+	Long: `Export a snapshot from the snapshot store as a self-contained zip backup with
+its manifest, in the same format the zip backups use. The zip can be restored,
+verified and copied like any other backup.`,
+	Example: `  eve-profile-sync backup export settings_Main_20251129-174512
+  eve-profile-sync backup export settings_Main_20251129-174512 --output-dir D:\EVE-Backups`,
+	Args: cobra.ExactArgs(1),
+	Run:  runBackupExport,
+}
+
+var backupMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move backups of older versions into the backup directory",
+	Long: `Move the backups that older versions wrote to a backup folder in the working
+directory or next to the executable into the backup directory, sorted by server
+and profile. This also happens before the first backup of a sync, copy-profile,
+restore or backup create is written; other commands never move backups.
+
+Only moved backups and the snapshot contents they use are removed from the old
+folder; other files in it are left in place.`,
+	Args: cobra.NoArgs,
+	Run:  runBackupMigrate,
+}
+
+var (
+	flagKeepLast    int
+	flagKeepDays    int
+	flagMaxSize     string
+	flagOutputDir   string
+	flagEncrypt     bool
+	flagPruneYes    bool
+	flagPruneDryRun bool
+	flagListProfile string
+	flagListTarget  string
+)
+
+func init() {
+	backupPruneCmd.Flags().BoolVarP(&flagPruneYes, "yes", "y", false, "remove without asking for confirmation")
+	backupPruneCmd.Flags().BoolVar(&flagPruneDryRun, "dry-run", false, "print which backups would be removed without removing anything")
+	backupPruneCmd.Flags().IntVar(&flagKeepLast, "keep-last", 0, "newest backups to keep per profile (default: backup_keep_last)")
+	backupPruneCmd.Flags().IntVar(&flagKeepDays, "keep-days", 0, "days for which the newest backup of each day is kept (default: backup_keep_days)")
+	backupPruneCmd.Flags().StringVar(&flagMaxSize, "max-size", "", "total size limit for all backups, e.g. 500MB (default: backup_max_size)")
+	backupListCmd.Flags().StringVar(&flagListProfile, "profile", "", "only list backups of this profile")
+	backupListCmd.Flags().StringVar(&flagListTarget, "target", "", "list the backups on this backup target instead of the backup directory")
+	backupExportCmd.Flags().StringVar(&flagOutputDir, "output-dir", "", "directory to write the zip backup to (default: the profile's backup folder)")
+	backupExportCmd.Flags().BoolVar(&flagEncrypt, "encrypt", false, "encrypt the zip backup with the backup passphrase")
+	backupCmd.AddCommand(backupListCmd, backupShowCmd, backupExportCmd, backupPruneCmd, backupMigrateCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+func runBackupPrune(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cmd.Flags().Changed("keep-last") {
+		cfg.BackupKeepLast = flagKeepLast
+	}
+	if cmd.Flags().Changed("keep-days") {
+		cfg.BackupKeepDays = flagKeepDays
+	}
+	if cmd.Flags().Changed("max-size") {
+		cfg.BackupMaxSize = flagMaxSize
+	}
+
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	root := backupRoot(cfg)
+	archives, err := backup.ListArchives(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	snapshots, err := backup.OpenStore(root).Snapshots()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	targets, err := backupTargets(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Zip backups, snapshots and the backups on each target are kept
+	// independently of each other
+	now := time.Now()
+	plan := append(backup.PlanPrune(archives, policy, now), backup.PlanPrune(snapshots, policy, now)...)
+	for _, t := range targets {
+		stored, err := backup.ListTarget(t)
+		if err != nil {
+			fmt.Printf("Warning: Failed to list backups on %s: %v\n", t.Name(), err)
+			continue
+		}
+		plan = append(plan, backup.PlanPrune(stored, policy, now)...)
+	}
+
+	if len(plan) == 0 {
+		fmt.Printf("No backups found in %s\n", root)
+		return
+	}
+
+	fmt.Printf("Retention policy: %s\n\n", policy)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tBACKUP\tPROFILE\tSIZE\tREASON")
+	var removeCount int
+	var removeSize int64
+	for _, e := range plan {
+		action := "keep"
+		if !e.Keep {
+			action = "remove"
+			removeCount++
+			removeSize += e.Size
+		}
+		path := e.Path
+		if e.Target != "" {
+			path = e.Target + ":" + e.Path
+		} else if rel, err := filepath.Rel(root, e.Path); err == nil {
+			path = rel
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", action, path, e.Profile, backup.FormatSize(e.Size), e.Reason)
+	}
+	w.Flush()
+	fmt.Println()
+
+	if flagPruneDryRun {
+		fmt.Printf("Dry run: %d backups (%s) would be removed\n", removeCount, backup.FormatSize(removeSize))
+		return
+	}
+
+	if removeCount == 0 {
+		fmt.Println("Nothing to remove")
+		return
+	}
+
+	if !flagPruneYes && !askConfirm(fmt.Sprintf("Remove %d backups (%s)?", removeCount, backup.FormatSize(removeSize))) {
+		fmt.Println("Operation cancelled.")
+		return
+	}
+
+	removed, err := pruneBackups(root, policy)
+	removed = append(removed, pruneTargets(cfg, policy)...)
+	printPruned(removed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runBackupList(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	root := backupRoot(cfg)
+	var archives []backup.Archive
+	if flagListTarget != "" {
+		t, findErr := findTarget(cfg, flagListTarget)
+		if findErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", findErr)
+			os.Exit(1)
+		}
+		root = t.Name()
+		archives, err = backup.ListTarget(t)
+	} else {
+		archives, err = listBackups(root)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	profileName := strings.TrimPrefix(flagListProfile, "settings_")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKUP\tKIND\tTAKEN\tPROFILE\tSERVER\tREASON\tSOURCE\tFILES\tSIZE")
+	count := 0
+	for _, a := range archives {
+		if profileName != "" && a.Profile != profileName {
+			continue
+		}
+		count++
+
+		server, reason, source, files := "-", "-", "-", "-"
+		if a.Server != "" && a.Server != backup.UnknownServer {
+			server = profile.ServerName(a.Server)
+		}
+
+		// Manifests are read from plain local backups only; backup show
+		// decrypts encrypted ones
+		if !a.Encrypted && a.Target == "" {
+			manifest, err := readBackupManifest(a, "")
+			switch {
+			case err == nil:
+				if manifest.Server != "" {
+					server = profile.ServerName(manifest.Server)
+				}
+				if manifest.Reason != "" {
+					reason = manifest.Reason
+				}
+				if manifest.UserID != "" || manifest.CharacterID != "" {
+					user, char := "-", "-"
+					if manifest.UserID != "" {
+						user = profile.UserFile{ID: manifest.UserID, Alias: cfg.UserAliases[manifest.UserID]}.Describe()
+					}
+					if manifest.CharacterID != "" {
+						char = profile.CharacterFile{ID: manifest.CharacterID, Alias: cfg.CharacterAliases[manifest.CharacterID]}.Describe()
+					}
+					source = fmt.Sprintf("user %s, char %s", user, char)
+				}
+				files = strconv.Itoa(len(manifest.Files))
+			case !errors.Is(err, backup.ErrNoManifest):
+				reason = "unreadable: " + err.Error()
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Name(), backupKind(a), a.Time.Format("2006-01-02 15:04"),
+			a.Profile, server, reason, source, files, backup.FormatSize(a.Size))
+	}
+
+	if count == 0 {
+		fmt.Printf("No backups found in %s\n", root)
+		return
+	}
+	w.Flush()
+}
+
+func runBackupShow(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	archive, err := findBackup(backupRoot(cfg), args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	passphrase, err := archivePassphrase(cfg, archive)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	manifest, err := readBackupManifest(archive, passphrase)
+	if errors.Is(err, backup.ErrNoManifest) {
+		printEntries(archive.Path, passphrase)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Backup: %s (%s)\n", archive.Path, backupKind(archive))
+	fmt.Printf("Created: %s\n", manifest.Created.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Tool version: %s\n", orDash(manifest.ToolVersion))
+	fmt.Printf("Profile: %s (%s)\n", manifest.Profile, manifest.ProfilePath)
+	if manifest.Server != "" {
+		server := profile.ServerFromPath(manifest.Server)
+		fmt.Printf("Server: %s\n", server.Label())
+	}
+	fmt.Printf("Reason: %s\n", orDash(manifest.Reason))
+	if manifest.UserID != "" {
+		user := profile.UserFile{ID: manifest.UserID, Alias: cfg.UserAliases[manifest.UserID]}
+		fmt.Printf("Source user ID: %s\n", user.Describe())
+	}
+	if manifest.CharacterID != "" {
+		char := profile.CharacterFile{ID: manifest.CharacterID, Alias: cfg.CharacterAliases[manifest.CharacterID]}
+		fmt.Printf("Source character ID: %s\n", char.Describe())
+	}
+	fmt.Printf("Files: %d (%s)\n\n", len(manifest.Files), backup.FormatSize(manifest.TotalSize()))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSIZE\tMODIFIED\tSHA-256")
+	for _, f := range manifest.Files {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", f.Name, f.Size, f.Modified.Format("2006-01-02 15:04:05"), f.SHA256)
+	}
+	w.Flush()
+}
+
+func runBackupExport(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	// A zip backup exported from a snapshot has the snapshot's name, so
+	// snapshots are looked up first
+	root := backupRoot(cfg)
+	snapshots, err := backup.OpenStore(root).Snapshots()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var archive *backup.Archive
+	for i, a := range snapshots {
+		if a.Path == args[0] || filepath.Base(a.Path) == filepath.Base(args[0]) || a.Name() == args[0] {
+			archive = &snapshots[i]
+			break
+		}
+	}
+	if archive == nil {
+		fmt.Fprintf(os.Stderr, "Error: snapshot %q not found in %s (use backup list)\n", args[0], root)
+		os.Exit(1)
+	}
+
+	passphrase := ""
+	if flagEncrypt {
+		if passphrase, err = backupPassphrase(cfg, true); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	zipPath, err := backup.OpenStore(root).Export(archive.Path, flagOutputDir, passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Export failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Exported %s to %s\n", archive.Name(), zipPath)
+}
+
+func runBackupMigrate(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	root := backupRoot(cfg)
+	moved, err := migrateLegacyBackups(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if moved == 0 {
+		fmt.Printf("No backups of older versions found in %s\n", strings.Join(legacyBackupDirs(), ", "))
+	}
+}
+
+// printEntries lists the files of a backup made before manifests existed
+func printEntries(archivePath, passphrase string) {
+	entries, err := backup.ListEntries(archivePath, passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Backup: %s\n", archivePath)
+	fmt.Println("No manifest: the backup was made by an older version")
+	fmt.Printf("Files: %d\n\n", len(entries))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSIZE\tMODIFIED")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", e.Name, e.Size, e.Modified.Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+}
+
+// listBackups returns the zip backups and snapshots in root, newest first
+func listBackups(root string) ([]backup.Archive, error) {
+	archives, err := backup.ListArchives(root)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := backup.OpenStore(root).Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	archives = append(archives, snapshots...)
+	backup.SortArchives(archives)
+	return archives, nil
+}
+
+// findBackup resolves a backup given as a path, or as a zip file or snapshot
+// name in the backup directory root
+func findBackup(root, name string) (backup.Archive, error) {
+	archives, err := listBackups(root)
+	if err != nil {
+		return backup.Archive{}, err
+	}
+
+	for _, a := range archives {
+		if a.Path == name || filepath.Base(a.Path) == filepath.Base(name) || a.Name() == name {
+			return a, nil
+		}
+	}
+
+	// A zip file outside the backup directory, possibly encrypted
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		return backup.Archive{Path: name, Encrypted: backup.IsEncrypted(name)}, nil
+	}
+
+	return backup.Archive{}, fmt.Errorf("backup %q not found in %s (use backup list)", name, root)
+}
+
+// readBackupManifest reads the manifest of a zip backup or snapshot
+func readBackupManifest(a backup.Archive, passphrase string) (*backup.Manifest, error) {
+	if a.Snapshot {
+		return backup.ReadSnapshot(a.Path)
+	}
+	manifest, err := backup.ReadManifest(a.Path, passphrase)
+	forgetPassphrase(err)
+	return manifest, err
+}
+
+// archivePassphrase returns the passphrase needed to open a backup, or an
+// empty string for backups that are not encrypted
+func archivePassphrase(cfg *config.Config, a backup.Archive) (string, error) {
+	if !a.Encrypted {
+		return "", nil
+	}
+	return backupPassphrase(cfg, false)
+}
+
+// backupKind names the storage format of a backup
+func backupKind(a backup.Archive) string {
+	switch {
+	case a.Snapshot:
+		return "snapshot"
+	case a.Encrypted:
+		return "encrypted"
+	}
+	return "zip"
+}
+
+// orDash returns "-" for empty values in tables
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// retentionPolicy builds the backup retention policy from the config
+func retentionPolicy(cfg *config.Config) (backup.Policy, error) {
+	maxSize, err := backup.ParseSize(cfg.BackupMaxSize)
+	if err != nil {
+		return backup.Policy{}, fmt.Errorf("invalid backup_max_size: %w", err)
+	}
+
+	return backup.Policy{
+		KeepLast: cfg.BackupKeepLast,
+		KeepDays: cfg.BackupKeepDays,
+		MaxSize:  maxSize,
+	}, nil
+}
+
+// enforceRetention removes old backups after a successful backup. Failures
+// only produce a warning: the new backup is already safe.
+func enforceRetention(cfg *config.Config) {
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		fmt.Printf("Warning: Old backups were not pruned: %v\n", err)
+		return
+	}
+	if policy.IsEmpty() {
+		return
+	}
+
+	removed, err := pruneBackups(backupRoot(cfg), policy)
+	removed = append(removed, pruneTargets(cfg, policy)...)
+	printPruned(removed)
+	if err != nil {
+		fmt.Printf("Warning: Failed to prune old backups: %v\n", err)
+	}
+}
+
+// pruneBackups applies the policy to the zip backups and snapshots in root
+func pruneBackups(root string, policy backup.Policy) ([]backup.Archive, error) {
+	removed, err := backup.Prune(root, policy)
+	if err != nil {
+		return removed, err
+	}
+
+	snapshots, err := backup.OpenStore(root).Prune(policy)
+	return append(removed, snapshots...), err
+}
+
+// printPruned reports removed backups
+func printPruned(removed []backup.Archive) {
+	if len(removed) == 0 {
+		return
+	}
+
+	var size int64
+	for _, a := range removed {
+		size += a.Size
+	}
+	fmt.Printf("Removed %d old backups (%s)\n", len(removed), backup.FormatSize(size))
+}
+
+// createBackup backs up a profile in the configured format: a zip archive,
+// encrypted if backup_encrypt is set, or a snapshot in the snapshot store.
+// Backups of older versions are moved into the backup directory first.
+func createBackup(cfg *config.Config, profilePath, profileName string, details backup.Details) (string, error) {
+	if _, err := migrateLegacyBackups(backupRoot(cfg)); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	switch cfg.BackupFormat {
+	case "", "zip":
+		passphrase := ""
+		if cfg.BackupEncrypt {
+			var err error
+			if passphrase, err = backupPassphrase(cfg, true); err != nil {
+				return "", err
+			}
+		}
+		return backup.CreateBackup(backupRoot(cfg), profilePath, profileName, details, passphrase)
+	case "snapshot":
+		if cfg.BackupEncrypt {
+			return "", fmt.Errorf("backup_encrypt requires backup_format zip; encrypt snapshots with backup export --encrypt")
+		}
+		return backup.OpenStore(backupRoot(cfg)).Snapshot(profilePath, profileName, details)
+	}
+	return "", fmt.Errorf("invalid backup_format %q (expected zip or snapshot)", cfg.BackupFormat)
+}
+
+// verifyBackup checks a backup made by createBackup in depth against the
+// profile. A backup that fails is removed, so that it is never listed,
+// replicated or restored as a good one.
+func verifyBackup(cfg *config.Config, backupPath, profilePath string) error {
+	err := checkBackup(cfg, backupPath, profilePath)
+	if err == nil {
+		return nil
+	}
+
+	// A snapshot's blobs stay for the garbage collection of the next prune
+	if rmErr := os.Remove(backupPath); rmErr != nil && !os.IsNotExist(rmErr) {
+		return fmt.Errorf("%w; the unverified backup %s could not be removed: %v", err, backupPath, rmErr)
+	}
+	return fmt.Errorf("%w; the unverified backup was removed", err)
+}
+
+// checkBackup verifies a zip backup or snapshot against the profile
+func checkBackup(cfg *config.Config, backupPath, profilePath string) error {
+	if filepath.Ext(backupPath) == ".json" {
+		return backup.OpenStore(backupRoot(cfg)).Verify(backupPath, profilePath)
+	}
+
+	passphrase := ""
+	if backup.IsEncrypted(backupPath) {
+		var err error
+		if passphrase, err = backupPassphrase(cfg, false); err != nil {
+			return err
+		}
+	}
+	err := backup.VerifyBackup(backupPath, profilePath, passphrase)
+	forgetPassphrase(err)
+	return err
+}
+
+// legacyBackupsChecked records that backups of older versions were looked
+// for in this run
+var legacyBackupsChecked bool
+
+// backupRoot returns the backup directory: backup_dir, or the per-user data
+// directory
+func backupRoot(cfg *config.Config) string {
+	if cfg.BackupDir != "" {
+		return cfg.BackupDir
+	}
+	return backup.DefaultDir()
+}
+
+// legacyBackupDirs returns the backup folders of older versions: in the
+// working directory and next to the executable
+func legacyBackupDirs() []string {
+	dirs := []string{backup.LegacyDir}
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(exe), backup.LegacyDir))
+	}
+	return dirs
+}
+
+// migrateLegacyBackups moves backups from the old ./backup locations into
+// root and returns how many were moved. It runs at most once per run: when
+// backup migrate is called, or before the first backup is written.
+func migrateLegacyBackups(root string) (int, error) {
+	if legacyBackupsChecked {
+		return 0, nil
+	}
+	legacyBackupsChecked = true
+
+	total := 0
+	var errs []error
+	for _, dir := range legacyBackupDirs() {
+		moved, err := backup.Migrate(dir, root)
+		if moved > 0 {
+			fmt.Printf("Moved %d backups from %s to %s\n", moved, dir, root)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to move old backups from %s: %w", dir, err))
+		}
+		total += moved
+	}
+	return total, errors.Join(errs...)
+}
+
+// backupDetails describes a backup taken on the server before an operation
+func backupDetails(server *profile.Server, reason string) backup.Details {
+	return backup.Details{
+		ToolVersion: Version,
+		Server:      server.DirName,
+		Reason:      reason,
+	}
+}
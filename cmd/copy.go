@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var copyProfileCmd = &cobra.Command{
+	Use:   "copy-profile",
+	Short: "Copy a profile's settings from one server directory into another",
+	Long: `Copy every file of a settings_* profile from one server directory into another,
+e.g. from Tranquility into Singularity. The destination profile is created if it
+does not exist; otherwise it is backed up before any file is replaced.`,
+	Args: cobra.NoArgs,
+	Run:  runCopyProfile,
+}
+
+var (
+	flagFromServer  string
+	flagToServer    string
+	flagCopyAs      string
+	flagCopyProfile string
+	flagCopyYes     bool
+	flagCopyDryRun  bool
+)
+
+func init() {
+	copyProfileCmd.Flags().StringVar(&flagFromServer, "from-server", "", "server to copy from: tq, sisi, thunderdome, serenity or a directory name")
+	copyProfileCmd.Flags().StringVar(&flagToServer, "to-server", "", "server to copy into")
+	copyProfileCmd.Flags().StringVar(&flagCopyProfile, "profile", "", "profile name without the settings_ prefix")
+	copyProfileCmd.Flags().StringVar(&flagCopyAs, "as", "", "destination profile name (default: same as the source)")
+	copyProfileCmd.Flags().BoolVarP(&flagCopyYes, "yes", "y", false, "run non-interactively: skip confirmation and resolve missing values from saved config")
+	copyProfileCmd.Flags().BoolVar(&flagCopyDryRun, "dry-run", false, "print which files would be copied without changing anything")
+	rootCmd.AddCommand(copyProfileCmd)
+}
+
+func runCopyProfile(cmd *cobra.Command, args []string) {
+	nonInteractive = flagCopyYes
+
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load config: %v\n", err)
+		cfg = &config.Config{}
+	}
+
+	// Step 1: Discover servers
+	servers, err := discoverServers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(servers) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No server directories found\n")
+		os.Exit(1)
+	}
+
+	// Step 2: Select source and destination servers
+	fromServer, err := selectServer(servers, "Select source server:", "from-server", flagFromServer, cfg.Server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	toServer, err := selectServer(servers, "Select destination server:", "to-server", flagToServer, cfg.TargetServer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Step 3: Select source profile
+	sourceProfile, err := selectProfile(fromServer.Path, flagCopyProfile, cfg.Profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	destName := sourceProfile.Name
+	if flagCopyAs != "" {
+		destName = flagCopyAs
+	}
+	destPath := filepath.Join(toServer.Path, "settings_"+destName)
+
+	if filepath.Clean(destPath) == filepath.Clean(sourceProfile.Path) {
+		fmt.Fprintf(os.Stderr, "Error: Source and destination profile are the same\n")
+		os.Exit(1)
+	}
+
+	// Step 4: Plan the copy
+	plan, err := sync.PlanProfileCopy(sourceProfile.Path, destPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if flagCopyDryRun {
+		fmt.Printf("Dry run for copying %s/%s to %s/%s (no files will be changed)\n\n", fromServer.Name, sourceProfile.Name, toServer.Name, destName)
+		printPlan(plan, newFileDetails(cfg))
+		return
+	}
+
+	// Step 5: Show summary and confirm
+	summary := fmt.Sprintf(`Copy Summary:
+  From: %s / %s
+  To: %s / %s
+  Files to copy: %d (%d unchanged)
+
+Existing files in the destination profile will be overwritten.`, fromServer.Name, sourceProfile.Name, toServer.Name, destName,
+		plan.Count(sync.ActionReplace), plan.Count(sync.ActionUnchanged))
+
+	if flagCopyYes {
+		fmt.Println(summary)
+		fmt.Println()
+	} else if !askConfirm(summary + "\n\nProceed?") {
+		fmt.Println("Operation cancelled.")
+		os.Exit(0)
+	}
+
+	// Step 6: Back up the destination profile if it already exists
+	if _, err := os.Stat(destPath); err == nil {
+		fmt.Printf("Creating backup of profile %s...\n", destName)
+		backupPath, err := createBackup(cfg, destPath, destName, backupDetails(toServer, "copy-profile"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to create backup: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := verifyBackup(cfg, backupPath, destPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Backup verification failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Backup created successfully: %s\n", backupPath)
+		replicateBackup(cfg, backupPath)
+		enforceRetention(cfg)
+	}
+
+	// Step 7: Copy files (all-or-nothing)
+	fmt.Println("Copying profile files...")
+	if err := sync.CopyProfile(sourceProfile.Path, destPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Copy failed: %v\n", err)
+		fmt.Println("No changes were applied to the destination profile.")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Profile copied successfully: %s\n", destPath)
+}
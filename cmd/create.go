@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"eve-profile-sync/internal/backup"
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/profile"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Back up profiles without changing anything",
+	Long: `Back up one, several or all settings_* profiles without syncing anything, e.g.
+before a patch day. Backups are always zip archives, encrypted if backup_encrypt
+is set, even with backup_format: snapshot, so that each one is self-contained.
+They are verified, replicated to the backup targets and pruned like the
+backups taken before a sync; a backup that fails verification is removed.
+
+Every selected profile is backed up even if another one fails; a summary of
+what was archived is printed at the end, and the exit code is non-zero if any
+profile could not be backed up. With --yes the command runs unattended, e.g.
+from Task Scheduler or cron.`,
+	Example: `  eve-profile-sync backup create
+  eve-profile-sync backup create --profile Main --profile Alts
+  eve-profile-sync backup create --all --yes
+  eve-profile-sync backup create --all-servers --yes --reason patch-day
+
+  # cron, every day at 03:00
+  0 3 * * * /usr/local/bin/eve-profile-sync backup create --all-servers --yes
+
+  # Task Scheduler, every Tuesday before downtime
+  schtasks /Create /TN "EVE profile backup" /SC WEEKLY /D TUE /ST 10:00 /TR "C:\Tools\eve-profile-sync.exe backup create --all-servers --yes"`,
+	Args: cobra.NoArgs,
+	Run:  runBackupCreate,
+}
+
+var (
+	flagCreateProfiles    []string
+	flagCreateAll         bool
+	flagAllServers        bool
+	flagCreateReason      string
+	flagCreateProfilesDir string
+	flagCreateServer      string
+	flagCreateYes         bool
+	flagCreateDryRun      bool
+)
+
+func init() {
+	backupCreateCmd.Flags().StringVar(&flagCreateProfilesDir, "profiles-dir", "", "EVE profiles directory (skips directory discovery)")
+	backupCreateCmd.Flags().StringVar(&flagCreateServer, "server", "", "server of the profiles: tq, sisi, thunderdome, serenity or a directory name")
+	backupCreateCmd.Flags().StringSliceVar(&flagCreateProfiles, "profile", nil, "profiles to back up, without the settings_ prefix (default: the saved profile with --yes)")
+	backupCreateCmd.Flags().BoolVar(&flagCreateAll, "all", false, "back up every settings_* profile of the server")
+	backupCreateCmd.Flags().BoolVar(&flagAllServers, "all-servers", false, "back up the profiles of every server directory found (all profiles unless --profile is given)")
+	backupCreateCmd.Flags().StringVar(&flagCreateReason, "reason", "backup", "reason recorded in the manifests, e.g. patch-day")
+	backupCreateCmd.Flags().BoolVarP(&flagCreateYes, "yes", "y", false, "run non-interactively: resolve missing values from saved config")
+	backupCreateCmd.Flags().BoolVar(&flagCreateDryRun, "dry-run", false, "print which profiles would be backed up without creating backups")
+	backupCmd.AddCommand(backupCreateCmd)
+}
+
+// createJob is a profile to back up, with the outcome once it has run
+type createJob struct {
+	Server  profile.Server
+	Profile profile.Profile
+	Path    string
+	Files   int
+	Size    int64
+	Err     error
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) {
+	nonInteractive = flagCreateYes
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load config: %v\n", err)
+		cfg = &config.Config{}
+	}
+
+	if flagAllServers && (flagCreateProfilesDir != "" || flagCreateServer != "") {
+		fmt.Fprintf(os.Stderr, "Error: --all-servers cannot be combined with --profiles-dir or --server\n")
+		os.Exit(1)
+	}
+
+	// Step 1: Select servers and profiles
+	var jobs []*createJob
+	if flagAllServers {
+		jobs, err = allServerJobs(flagCreateProfiles)
+	} else {
+		jobs, err = serverJobs(cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if flagCreateDryRun {
+		fmt.Printf("Dry run: %d profiles would be backed up to %s\n", len(jobs), backupRoot(cfg))
+		for _, job := range jobs {
+			fmt.Printf("  %s / %s (%s)\n", job.Server.Name, job.Profile.Name, job.Profile.Path)
+		}
+		return
+	}
+
+	// Step 2: Back up every profile, even if another one fails
+	failed := 0
+	for _, job := range jobs {
+		fmt.Printf("Creating backup of profile %s on %s...\n", job.Profile.Name, job.Server.Name)
+		if job.Err = runCreateJob(cfg, job); job.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s / %s: %v\n", job.Server.Name, job.Profile.Name, job.Err)
+			failed++
+			continue
+		}
+		fmt.Printf("Backup created successfully: %s\n", job.Path)
+		replicateBackup(cfg, job.Path)
+	}
+
+	if failed < len(jobs) {
+		enforceRetention(cfg)
+	}
+
+	// Step 3: Summary
+	printCreateSummary(backupRoot(cfg), jobs)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d profiles could not be backed up\n", failed, len(jobs))
+		os.Exit(1)
+	}
+}
+
+// serverJobs selects the profiles to back up on a single server: those given
+// with --profile, all of them with --all, or the ones the user picks
+func serverJobs(cfg *config.Config) ([]*createJob, error) {
+	server, _, err := discoverServer(flagCreateProfilesDir, flagCreateServer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := profile.ListProfiles(server.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no settings_* profiles found in %s", server.Path)
+	}
+
+	options := make([]string, len(profiles))
+	for i, p := range profiles {
+		options[i] = p.Name
+	}
+
+	var names []string
+	switch {
+	case len(flagCreateProfiles) > 0:
+		names = flagCreateProfiles
+	case flagCreateAll || len(profiles) == 1:
+		names = options
+	case flagCreateYes:
+		if cfg.Profile == "" {
+			return nil, fmt.Errorf("cannot resolve profiles non-interactively: pass --profile or --all")
+		}
+		names = []string{cfg.Profile}
+	default:
+		var defaults []string
+		for _, name := range options {
+			if name == cfg.Profile {
+				defaults = []string{name}
+			}
+		}
+		names, err = multiSelectWithFallback("Select profiles to back up:", options, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select profiles: %w", err)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no profiles selected (pass --profile or --all)")
+	}
+
+	var jobs []*createJob
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "settings_")
+		p := findProfile(profiles, name)
+		if p == nil {
+			return nil, fmt.Errorf("profile %q not found in %s (available: %s)", name, server.Path, strings.Join(options, ", "))
+		}
+		jobs = append(jobs, &createJob{Server: *server, Profile: *p})
+	}
+	return jobs, nil
+}
+
+// allServerJobs selects the profiles to back up on every server directory:
+// all of them, or those named in names wherever they exist. A named profile
+// must exist on at least one server.
+func allServerJobs(names []string) ([]*createJob, error) {
+	servers, err := discoverServers()
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no server directories found")
+	}
+
+	found := make(map[string]bool)
+	var jobs []*createJob
+	for _, server := range servers {
+		profiles, err := profile.ListProfiles(server.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list profiles of %s: %w", server.Label(), err)
+		}
+
+		if len(names) == 0 {
+			for _, p := range profiles {
+				jobs = append(jobs, &createJob{Server: server, Profile: p})
+			}
+			continue
+		}
+
+		for _, name := range names {
+			name = strings.TrimPrefix(name, "settings_")
+			if p := findProfile(profiles, name); p != nil {
+				jobs = append(jobs, &createJob{Server: server, Profile: *p})
+				found[name] = true
+			}
+		}
+	}
+
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "settings_")
+		if !found[name] {
+			return nil, fmt.Errorf("profile %q not found on any server", name)
+		}
+	}
+
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no settings_* profiles found on any server")
+	}
+	return jobs, nil
+}
+
+// findProfile returns the profile with the given name, or nil
+func findProfile(profiles []profile.Profile, name string) *profile.Profile {
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// runCreateJob backs up and verifies one profile and records what was archived.
+// The backup is a zip archive whatever backup_format says.
+func runCreateJob(cfg *config.Config, job *createJob) error {
+	zipCfg := *cfg
+	zipCfg.BackupFormat = "zip"
+	backupPath, err := createBackup(&zipCfg, job.Profile.Path, job.Profile.Name, backupDetails(&job.Server, flagCreateReason))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if err := verifyBackup(cfg, backupPath, job.Profile.Path); err != nil {
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	job.Path = backupPath
+
+	// The manifest was just verified, so reading it again only gives the
+	// counts for the summary
+	archive := backup.Archive{Path: backupPath, Encrypted: backup.IsEncrypted(backupPath)}
+	passphrase, err := archivePassphrase(cfg, archive)
+	if err == nil {
+		if manifest, err := readBackupManifest(archive, passphrase); err == nil {
+			job.Files = len(manifest.Files)
+			job.Size = manifest.TotalSize()
+		}
+	}
+	return nil
+}
+
+// printCreateSummary reports every profile with its backup, or why it failed
+func printCreateSummary(root string, jobs []*createJob) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tPROFILE\tFILES\tSIZE\tBACKUP")
+
+	created := 0
+	var files int
+	var size int64
+	for _, job := range jobs {
+		if job.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\tFAILED: %v\n", job.Server.Name, job.Profile.Name, job.Err)
+			continue
+		}
+
+		created++
+		files += job.Files
+		size += job.Size
+		name := job.Path
+		if rel, err := filepath.Rel(root, job.Path); err == nil {
+			name = rel
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", job.Server.Name, job.Profile.Name, job.Files, backup.FormatSize(job.Size), name)
+	}
+	w.Flush()
+
+	fmt.Printf("\nArchived %d of %d profiles (%d files, %s) in %s\n", created, len(jobs), files, backup.FormatSize(size), root)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"eve-profile-sync/internal/settings"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <file-a> <file-b>",
+	Short: "Show how two settings files differ",
+	Long: `Decode two core_user_*.dat or core_char_*.dat files and list the settings that
+were added, removed or changed, by key path (e.g. overview.tabs or windows.chat[2]).
+Files that cannot be decoded are compared byte by byte instead.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+var flagDiffRaw bool
+
+// maxByteRanges limits how many differing byte runs the raw diff prints
+const maxByteRanges = 20
+
+func init() {
+	diffCmd.Flags().BoolVar(&flagDiffRaw, "raw", false, "compare the files byte by byte without decoding them")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	pathA, pathB := args[0], args[1]
+
+	dataA, err := os.ReadFile(pathA)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to read %s: %v\n", pathA, err)
+		os.Exit(1)
+	}
+
+	dataB, err := os.ReadFile(pathB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to read %s: %v\n", pathB, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n\n", pathA, pathB)
+
+	if !flagDiffRaw {
+		valueA, errA := settings.Decode(dataA)
+		valueB, errB := settings.Decode(dataB)
+		if errA == nil && errB == nil {
+			changes, err := settings.Diff(valueA, valueB)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			printSettingsDiff(changes)
+			return
+		}
+
+		if errA != nil {
+			fmt.Printf("Warning: Could not decode %s: %v\n", pathA, errA)
+		}
+		if errB != nil {
+			fmt.Printf("Warning: Could not decode %s: %v\n", pathB, errB)
+		}
+		fmt.Println("Falling back to a byte-level comparison.")
+		fmt.Println()
+	}
+
+	printByteDiff(dataA, dataB)
+}
+
+// printSettingsDiff prints one line per changed key path
+func printSettingsDiff(changes []settings.Change) {
+	if len(changes) == 0 {
+		fmt.Println("No differences.")
+		return
+	}
+
+	counts := make(map[settings.ChangeKind]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+		switch c.Kind {
+		case settings.ChangeAdded:
+			fmt.Printf("+ %s: %s\n", c.Path, settings.FormatValue(c.New))
+		case settings.ChangeRemoved:
+			fmt.Printf("- %s: %s\n", c.Path, settings.FormatValue(c.Old))
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", c.Path, settings.FormatValue(c.Old), settings.FormatValue(c.New))
+		}
+	}
+
+	fmt.Printf("\n%d added, %d removed, %d changed\n",
+		counts[settings.ChangeAdded], counts[settings.ChangeRemoved], counts[settings.ChangeModified])
+}
+
+// printByteDiff prints the runs of differing bytes as hex
+func printByteDiff(a, b []byte) {
+	ranges := settings.DiffBytes(a, b)
+	if len(ranges) == 0 {
+		fmt.Println("Files are identical.")
+		return
+	}
+
+	differing := 0
+	for i, r := range ranges {
+		differing += max(len(r.Old), len(r.New))
+		if i >= maxByteRanges {
+			continue
+		}
+		fmt.Printf("@ 0x%08x\n", r.Offset)
+		if len(r.Old) > 0 {
+			fmt.Printf("- %s\n", hexPreview(r.Old))
+		}
+		if len(r.New) > 0 {
+			fmt.Printf("+ %s\n", hexPreview(r.New))
+		}
+	}
+
+	if len(ranges) > maxByteRanges {
+		fmt.Printf("... %d more differing ranges\n", len(ranges)-maxByteRanges)
+	}
+
+	fmt.Printf("\nSizes: %d and %d bytes, %d bytes differ in %d ranges\n", len(a), len(b), differing, len(ranges))
+}
+
+// hexPreview renders up to 32 bytes as hex
+func hexPreview(data []byte) string {
+	const limit = 32
+	if len(data) <= limit {
+		return hex.EncodeToString(data)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", hex.EncodeToString(data[:limit]), len(data))
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"eve-profile-sync/internal/settings"
+
+	"github.com/spf13/cobra"
+)
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump <file>",
+	Short: "Print the decoded contents of a settings file",
+	Long: `Decode a core_user_*.dat or core_char_*.dat file and print its full settings
+tree as JSON or YAML. Dict keys are sorted, so dumps of two files can be compared
+with any text diff tool.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runDump,
+}
+
+var (
+	flagDumpFormat string
+	flagDumpOutput string
+)
+
+func init() {
+	dumpCmd.Flags().StringVar(&flagDumpFormat, "format", "json", "output format: json or yaml")
+	dumpCmd.Flags().StringVarP(&flagDumpOutput, "output", "o", "", "write to this file instead of standard output")
+	rootCmd.AddCommand(dumpCmd)
+}
+
+func runDump(cmd *cobra.Command, args []string) {
+	write, err := dumpWriter(flagDumpFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	value, err := settings.DecodeFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if flagDumpOutput != "" {
+		out, err = os.Create(flagDumpOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to create output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	if err := write(out, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// dumpWriter returns the renderer for an output format
+func dumpWriter(format string) (func(io.Writer, settings.Value) error, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return settings.WriteJSON, nil
+	case "yaml", "yml":
+		return settings.WriteYAML, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (use json or yaml)", format)
+}
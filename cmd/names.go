@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/profile"
+)
+
+// nameSources returns the sources used to look up character names, in the
+// order they are asked
+func nameSources(cfg *config.Config) []profile.NameSource {
+	logDirs := profile.FindLogDirectories()
+	if flagLogsDir != "" {
+		logDirs = []string{flagLogsDir}
+	} else if cfg.LogsDir != "" {
+		logDirs = []string{cfg.LogsDir}
+	}
+
+	sources := []profile.NameSource{profile.NewLogNames(logDirs)}
+
+	// Characters without logs are looked up through ESI
+	if cfg.ESIEnabled {
+		sources = append(sources, profile.NewESINames(esiURL(cfg), nameCachePath(cfg), nameCacheTTL(cfg)))
+	}
+
+	return sources
+}
+
+func esiURL(cfg *config.Config) string {
+	if cfg.ESIURL == "" {
+		return profile.DefaultESIURL
+	}
+	return cfg.ESIURL
+}
+
+func nameCachePath(cfg *config.Config) string {
+	if cfg.NameCache == "" {
+		return profile.DefaultNameCachePath()
+	}
+	return cfg.NameCache
+}
+
+func nameCacheTTL(cfg *config.Config) time.Duration {
+	if cfg.NameCacheTTL == "" {
+		return profile.DefaultNameCacheTTL
+	}
+
+	ttl, err := time.ParseDuration(cfg.NameCacheTTL)
+	if err != nil {
+		fmt.Printf("Warning: Invalid name_cache_ttl %q, using %s\n", cfg.NameCacheTTL, profile.DefaultNameCacheTTL)
+		return profile.DefaultNameCacheTTL
+	}
+	return ttl
+}
+
+// fileDetails fills in the names and aliases shown next to file IDs
+type fileDetails struct {
+	names            []profile.NameSource
+	userAliases      map[string]string
+	characterAliases map[string]string
+}
+
+func newFileDetails(cfg *config.Config) *fileDetails {
+	return &fileDetails{
+		names:            nameSources(cfg),
+		userAliases:      cfg.UserAliases,
+		characterAliases: cfg.CharacterAliases,
+	}
+}
+
+// userFiles sets the aliases of user files; account names cannot be looked up
+func (d *fileDetails) userFiles(files []profile.UserFile) {
+	profile.ApplyUserAliases(files, d.userAliases)
+}
+
+// characterFiles sets the names and aliases of character files
+func (d *fileDetails) characterFiles(files []profile.CharacterFile) {
+	profile.ApplyCharacterAliases(files, d.characterAliases)
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.Name == "" && !seen[f.ID] {
+			seen[f.ID] = true
+			ids = append(ids, f.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	names, err := profile.ResolveNames(d.names, ids)
+	if err != nil {
+		fmt.Printf("Warning: Failed to look up some character names: %v\n", err)
+	}
+
+	profile.ApplyCharacterNames(files, names)
+}
+
+// fileAlias returns the alias registered for a core_user_ or core_char_ file
+// name, or "" if there is none. A nil receiver has no aliases.
+func (d *fileDetails) fileAlias(name string) string {
+	if d == nil {
+		return ""
+	}
+	if id, err := profile.ExtractUserID(name); err == nil {
+		return d.userAliases[id]
+	}
+	if id, err := profile.ExtractCharacterID(name); err == nil {
+		return d.characterAliases[id]
+	}
+	return ""
+}
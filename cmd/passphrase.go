@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"eve-profile-sync/internal/backup"
+	"eve-profile-sync/internal/config"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// passphraseEnv is the environment variable holding the backup passphrase
+const passphraseEnv = "EVE_PROFILE_SYNC_PASSPHRASE"
+
+// cachedPassphrase keeps a prompted passphrase for the rest of the run, so
+// that backing up several profiles asks only once. It is dropped again when
+// it fails to decrypt a backup.
+var cachedPassphrase string
+
+// backupPassphrase returns the passphrase for encrypted backups, from
+// EVE_PROFILE_SYNC_PASSPHRASE, the configured passphrase file or a prompt.
+// A passphrase for a new backup (confirm) is prompted for twice.
+func backupPassphrase(cfg *config.Config, confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if cfg.BackupPassphraseFile != "" {
+		data, err := os.ReadFile(cfg.BackupPassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		passphrase := strings.TrimRight(string(data), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase file %s is empty", cfg.BackupPassphraseFile)
+		}
+		return passphrase, nil
+	}
+
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+
+	if nonInteractive {
+		return "", fmt.Errorf("backup passphrase required: set %s or backup_passphrase_file", passphraseEnv)
+	}
+
+	passphrase, err := askPassword("Backup passphrase:")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("empty passphrase")
+	}
+
+	if confirm {
+		repeated, err := askPassword("Repeat passphrase:")
+		if err != nil {
+			return "", err
+		}
+		if repeated != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	cachedPassphrase = passphrase
+	return passphrase, nil
+}
+
+// forgetPassphrase drops the prompted passphrase if err shows that it did not
+// decrypt a backup, so that it is asked for again instead of being reused,
+// for example to encrypt a new backup
+func forgetPassphrase(err error) {
+	if errors.Is(err, backup.ErrWrongPassphrase) {
+		cachedPassphrase = ""
+	}
+}
+
+// askPassword attempts to use survey.Password, but falls back to reading a
+// line from stdin if the interactive terminal is not available
+func askPassword(message string) (string, error) {
+	var password string
+	err := survey.AskOne(&survey.Password{Message: message}, &password, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr))
+	if err == nil {
+		return password, nil
+	}
+
+	if isTerminalError(err) {
+		fmt.Fprintf(os.Stdout, "%s (input is visible) ", message)
+		return readLine()
+	}
+
+	return "", fmt.Errorf("failed to read passphrase: %w", err)
+}
@@ -0,0 +1,382 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"eve-profile-sync/internal/backup"
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/profile"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a profile from a backup",
+	Long: `Restore a settings_* profile from one of its backups, either completely or only
+selected core_user_*.dat and core_char_*.dat files. A safety backup of the
+current profile is taken first, and every restored file is hashed and compared
+with the archived content afterwards.`,
+	Example: `  eve-profile-sync restore --profile Main --list
+  eve-profile-sync restore --profile Main
+  eve-profile-sync restore --profile Main --backup settings_Main_20251129-174512.zip --file 9876543210 --yes
+  eve-profile-sync restore --profile Main --target nas`,
+	Args: cobra.NoArgs,
+	Run:  runRestore,
+}
+
+var (
+	flagRestoreList        bool
+	flagRestoreBackup      string
+	flagRestoreFiles       []string
+	flagRestoreProfilesDir string
+	flagRestoreServer      string
+	flagRestoreProfile     string
+	flagRestoreTarget      string
+	flagRestoreYes         bool
+)
+
+func init() {
+	restoreCmd.Flags().StringVar(&flagRestoreProfilesDir, "profiles-dir", "", "EVE profiles directory (skips directory discovery)")
+	restoreCmd.Flags().StringVar(&flagRestoreServer, "server", "", "server of the profile: tq, sisi, thunderdome, serenity or a directory name")
+	restoreCmd.Flags().StringVar(&flagRestoreProfile, "profile", "", "profile name without the settings_ prefix")
+	restoreCmd.Flags().BoolVar(&flagRestoreList, "list", false, "list the backups of the profile and exit")
+	restoreCmd.Flags().StringVar(&flagRestoreBackup, "backup", "", "backup file name or path (default: the newest backup with --yes)")
+	restoreCmd.Flags().StringVar(&flagRestoreTarget, "target", "", "restore from a backup on this backup target instead of the backup directory")
+	restoreCmd.Flags().StringSliceVar(&flagRestoreFiles, "file", nil, "restore only these files, given as file names or user/character IDs (default: the whole profile with --yes)")
+	restoreCmd.Flags().BoolVarP(&flagRestoreYes, "yes", "y", false, "run non-interactively: skip confirmation and resolve missing values from saved config")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	nonInteractive = flagRestoreYes
+
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load config: %v\n", err)
+		cfg = &config.Config{}
+	}
+
+	// Step 1: Select server and profile
+	server, _, err := discoverServer(flagRestoreProfilesDir, flagRestoreServer, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	selectedProfile, err := selectProfile(server.Path, flagRestoreProfile, cfg.Profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Step 2: Find the backups of the profile
+	root := backupRoot(cfg)
+	location := root
+	var all []backup.Archive
+	if flagRestoreTarget != "" {
+		t, findErr := findTarget(cfg, flagRestoreTarget)
+		if findErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", findErr)
+			os.Exit(1)
+		}
+		location = t.Name()
+		all, err = backup.ListTarget(t)
+	} else {
+		all, err = listBackups(root)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	archives := profileArchives(all, server.DirName, selectedProfile.Name)
+	if len(archives) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No backups of profile %s found in %s\n", selectedProfile.Name, location)
+		os.Exit(1)
+	}
+
+	if flagRestoreList {
+		printArchives(archives)
+		return
+	}
+
+	// Step 3: Select the backup
+	archive, err := selectArchive(archives, flagRestoreBackup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Backups on a target are downloaded to a temp copy, which is removed
+	// once its content is read
+	cleanup := func() {}
+	backupLabel := archive.Path
+	if archive.Target != "" {
+		backupLabel = archive.Target + ":" + archive.Path
+		fmt.Printf("Downloading %s from %s...\n", archive.Name(), archive.Target)
+		cleanup, err = fetchBackup(cfg, archive)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Step 4: Select the files to restore
+	passphrase, err := archivePassphrase(cfg, *archive)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	entries, err := backupEntries(archive, passphrase)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	names, err := selectRestoreFiles(entries, flagRestoreFiles, newFileDetails(cfg))
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Step 5: Read and check the archived content before touching anything
+	restoration, err := prepareRestore(root, archive, names, passphrase)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cleanup()
+
+	// Step 6: Show summary and confirm
+	files := fmt.Sprintf("whole profile (%d files)", len(entries))
+	if len(names) > 0 {
+		files = strings.Join(names, ", ")
+	}
+	summary := fmt.Sprintf(`Restore Summary:
+  Server: %s
+  Profile: %s
+  Backup: %s (taken %s)
+  Files: %s
+
+Restored files replace the current ones; files not in the backup are kept.
+A safety backup of the profile will be created before making any changes.`, server.Name, selectedProfile.Name,
+		backupLabel, archive.Time.Format("2006-01-02 15:04"), files)
+
+	if flagRestoreYes {
+		fmt.Println(summary)
+		fmt.Println()
+	} else if !askConfirm(summary + "\n\nProceed?") {
+		fmt.Println("Operation cancelled.")
+		os.Exit(0)
+	}
+
+	// Step 7: Safety backup of the current state
+	fmt.Printf("Creating safety backup of profile %s...\n", selectedProfile.Name)
+	safetyPath, err := createBackup(cfg, selectedProfile.Path, selectedProfile.Name, backupDetails(server, "restore"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to create backup: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := verifyBackup(cfg, safetyPath, selectedProfile.Path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Backup verification failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Backup created successfully: %s\n", safetyPath)
+	replicateBackup(cfg, safetyPath)
+
+	// Step 8: Restore and verify
+	fmt.Println("Restoring files...")
+	restored, err := restoration.Apply(selectedProfile.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Restore failed: %v\n", err)
+		fmt.Printf("The profile state before restoring is saved in %s\n", safetyPath)
+		os.Exit(1)
+	}
+
+	enforceRetention(cfg)
+
+	fmt.Printf("Restored and verified %d files from %s\n", len(restored), archive.Name())
+}
+
+// profileArchives filters the backups of a profile on a server. Backups
+// whose server is not known are included.
+func profileArchives(all []backup.Archive, server, profileName string) []backup.Archive {
+	var archives []backup.Archive
+	for _, a := range all {
+		if a.Profile == profileName && (a.Server == server || a.Server == "" || a.Server == backup.UnknownServer) {
+			archives = append(archives, a)
+		}
+	}
+	return archives
+}
+
+// printArchives prints backups as a table
+func printArchives(archives []backup.Archive) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKUP\tKIND\tTAKEN\tSIZE")
+	for _, a := range archives {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name(), backupKind(a), a.Time.Format("2006-01-02 15:04"), backup.FormatSize(a.Size))
+	}
+	w.Flush()
+}
+
+// backupEntries lists the files of a zip backup or snapshot
+func backupEntries(a *backup.Archive, passphrase string) ([]backup.Entry, error) {
+	if a.Snapshot {
+		return backup.SnapshotEntries(a.Path)
+	}
+	entries, err := backup.ListEntries(a.Path, passphrase)
+	forgetPassphrase(err)
+	return entries, err
+}
+
+// prepareRestore reads the files to restore from a zip backup or snapshot
+func prepareRestore(root string, a *backup.Archive, names []string, passphrase string) (*backup.Restoration, error) {
+	if a.Snapshot {
+		return backup.OpenStore(root).PrepareRestore(a.Path, names)
+	}
+	restoration, err := backup.PrepareRestore(a.Path, names, passphrase)
+	forgetPassphrase(err)
+	return restoration, err
+}
+
+// selectArchive picks the backup to restore: the one named by --backup, the
+// newest one in non-interactive mode, or the user's choice
+func selectArchive(archives []backup.Archive, flagBackup string) (*backup.Archive, error) {
+	if flagBackup != "" {
+		for i, a := range archives {
+			if a.Path == flagBackup || filepath.Base(a.Path) == filepath.Base(flagBackup) || a.Name() == flagBackup {
+				return &archives[i], nil
+			}
+		}
+		return nil, fmt.Errorf("backup %q not found among the backups of this profile (use --list)", flagBackup)
+	}
+
+	if nonInteractive {
+		return &archives[0], nil
+	}
+
+	options := make([]string, len(archives))
+	for i, a := range archives {
+		options[i] = fmt.Sprintf("%s (%s, %s, %s)", a.Name(), backupKind(a), a.Time.Format("2006-01-02 15:04"), backup.FormatSize(a.Size))
+	}
+
+	selected, err := selectWithFallback("Select backup:", options, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select backup: %w", err)
+	}
+
+	for i, option := range options {
+		if option == selected {
+			return &archives[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("selected backup not found")
+}
+
+// selectRestoreFiles resolves the entries to restore. It returns nil for the
+// whole profile.
+func selectRestoreFiles(entries []backup.Entry, flagFiles []string, details *fileDetails) ([]string, error) {
+	if len(flagFiles) > 0 {
+		return matchEntries(entries, flagFiles)
+	}
+
+	if nonInteractive {
+		return nil, nil
+	}
+
+	const (
+		wholeProfile  = "Whole profile"
+		selectedFiles = "Selected user and character files"
+	)
+	scope, err := selectWithFallback("Restore:", []string{wholeProfile, selectedFiles}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select restore scope: %w", err)
+	}
+	if scope == wholeProfile {
+		return nil, nil
+	}
+
+	var options, names []string
+	for _, e := range entries {
+		if !e.IsCore() {
+			continue
+		}
+		label := e.Name
+		if alias := details.fileAlias(filepath.Base(e.Name)); alias != "" {
+			label = fmt.Sprintf("%s (Alias: %s)", e.Name, alias)
+		}
+		options = append(options, label)
+		names = append(names, e.Name)
+	}
+
+	if len(options) == 0 {
+		return nil, fmt.Errorf("backup contains no user or character files")
+	}
+
+	selected, err := multiSelectWithFallback("Select files to restore:", options, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select files: %w", err)
+	}
+
+	var result []string
+	for i, option := range options {
+		if slices.Contains(selected, option) {
+			result = append(result, names[i])
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no files selected")
+	}
+
+	return result, nil
+}
+
+// matchEntries resolves --file values: entry names, or user and character
+// IDs matching core_user_{ID}.dat and core_char_{ID}.dat
+func matchEntries(entries []backup.Entry, values []string) ([]string, error) {
+	var names []string
+	for _, value := range values {
+		matched := false
+		for _, e := range entries {
+			base := filepath.Base(e.Name)
+			if e.Name == value || base == value || (e.IsCore() && entryID(base) == value) {
+				if !slices.Contains(names, e.Name) {
+					names = append(names, e.Name)
+				}
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no file matching %q in the backup", value)
+		}
+	}
+	return names, nil
+}
+
+// entryID returns the ID of a core_user_ or core_char_ file name
+func entryID(name string) string {
+	if id, err := profile.ExtractUserID(name); err == nil {
+		return id
+	}
+	if id, err := profile.ExtractCharacterID(name); err == nil {
+		return id
+	}
+	return ""
+}
@@ -4,18 +4,24 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 
-	"eve-profile-sync/internal/backup"
 	"eve-profile-sync/internal/config"
 	"eve-profile-sync/internal/profile"
+	"eve-profile-sync/internal/settings"
 	"eve-profile-sync/internal/sync"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 )
 
+// Version is the release version, set at build time with
+// -ldflags "-X eve-profile-sync/cmd.Version=1.2.3"
+var Version = "dev"
+
 var rootCmd = &cobra.Command{
 	Use:   "eve-profile-sync",
 	Short: "Synchronize EVE Online profile settings",
@@ -24,6 +30,51 @@ by replacing all user and character configuration files with selected ones.`,
 	Run: runSync,
 }
 
+var (
+	flagProfilesDir string
+	flagServer      string
+	flagProfile     string
+	flagUserID      string
+	flagCharacterID string
+	flagYes         bool
+	flagDryRun      bool
+
+	flagTargetServer   string
+	flagTargetProfiles []string
+	flagIncludeUsers   []string
+	flagExcludeUsers   []string
+	flagIncludeChars   []string
+	flagExcludeChars   []string
+	flagOnly           []string
+	flagExcept         []string
+	flagLogsDir        string
+)
+
+// nonInteractive is set from the --yes flag of the running command. Prompt
+// helpers shared by the commands resolve missing values from saved config
+// instead of asking when it is set.
+var nonInteractive bool
+
+func init() {
+	rootCmd.Version = Version
+	rootCmd.Flags().StringVar(&flagProfilesDir, "profiles-dir", "", "EVE profiles directory (skips directory discovery)")
+	rootCmd.Flags().StringVar(&flagServer, "server", "", "server to sync from: tq, sisi, thunderdome, serenity or a directory name (skips server selection)")
+	rootCmd.Flags().StringVar(&flagProfile, "profile", "", "profile name without the settings_ prefix (skips profile selection)")
+	rootCmd.Flags().StringVar(&flagUserID, "user", "", "source user ID from core_user_{ID}.dat (skips user file selection)")
+	rootCmd.Flags().StringVar(&flagCharacterID, "char", "", "source character ID from core_char_{ID}.dat (skips character file selection)")
+	rootCmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "run non-interactively: skip confirmation and resolve missing values from saved config")
+	rootCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "print which files would be replaced without changing anything")
+	rootCmd.Flags().StringVar(&flagTargetServer, "target-server", "", "server whose profiles receive the source settings (default: the source server)")
+	rootCmd.Flags().StringSliceVar(&flagTargetProfiles, "target-profile", nil, "profiles that receive the source settings (default: the source profile)")
+	rootCmd.Flags().StringSliceVar(&flagIncludeUsers, "include-user", nil, "only replace these user IDs (skips target selection)")
+	rootCmd.Flags().StringSliceVar(&flagExcludeUsers, "exclude-user", nil, "never replace these user IDs (skips target selection)")
+	rootCmd.Flags().StringSliceVar(&flagIncludeChars, "include-char", nil, "only replace these character IDs (skips target selection)")
+	rootCmd.Flags().StringSliceVar(&flagExcludeChars, "exclude-char", nil, "never replace these character IDs (skips target selection)")
+	rootCmd.Flags().StringSliceVar(&flagOnly, "only", nil, "merge only these settings categories instead of replacing whole files: "+strings.Join(settings.CategoryNames(), ", "))
+	rootCmd.Flags().StringSliceVar(&flagExcept, "except", nil, "merge all settings except these categories instead of replacing whole files")
+	rootCmd.Flags().StringVar(&flagLogsDir, "logs-dir", "", "EVE logs directory used to look up character names (default: discovered)")
+}
+
 // Execute runs the root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -33,6 +84,8 @@ func Execute() {
 }
 
 func runSync(cmd *cobra.Command, args []string) {
+	nonInteractive = flagYes
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -40,15 +93,22 @@ func runSync(cmd *cobra.Command, args []string) {
 		cfg = &config.Config{}
 	}
 
-	// Step 1: Discover profiles directory
-	profilesDir, err := discoverProfilesDirectory(cfg.ProfilesDir)
+	categories, err := settings.ParseSelection(flagOnly, flagExcept)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Step 1: Discover server and profiles directory
+	server, servers, err := discoverServer(flagProfilesDir, flagServer, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	profilesDir := server.Path
 
 	// Step 2: Select profile
-	selectedProfile, err := selectProfile(profilesDir, cfg.Profile)
+	selectedProfile, err := selectProfile(profilesDir, flagProfile, cfg.Profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -61,12 +121,15 @@ func runSync(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	details := newFileDetails(cfg)
+	details.userFiles(userFiles)
+
 	if len(userFiles) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: No user files found in profile\n")
 		os.Exit(1)
 	}
 
-	selectedUserFile, err := selectUserFile(userFiles, cfg.UserID)
+	selectedUserFile, err := selectUserFile(userFiles, flagUserID, cfg.UserID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -79,65 +142,152 @@ func runSync(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	details.characterFiles(charFiles)
+
 	if len(charFiles) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: No character files found in profile\n")
 		os.Exit(1)
 	}
 
-	selectedCharFile, err := selectCharacterFile(charFiles, cfg.CharacterID)
+	selectedCharFile, err := selectCharacterFile(charFiles, flagCharacterID, cfg.CharacterID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Step 5: Show summary and confirm
-	if !confirmOperation(selectedProfile, selectedUserFile, selectedCharFile) {
-		fmt.Println("Operation cancelled.")
-		os.Exit(0)
+	// Step 5: Select target server and profiles
+	savedSource := isSavedSource(server, cfg)
+	targetServer, err := selectTargetServer(servers, server, flagTargetServer, cfg.TargetServer, savedSource)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Step 6: Validate operation
-	if err := sync.ValidateOperation(selectedProfile.Path, selectedUserFile.Path, selectedCharFile.Path); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Validation failed: %v\n", err)
+	// Saved target profiles belong to the saved source and target servers;
+	// an empty target_server stands for the source server
+	savedTargets := cfg.TargetProfiles
+	savedTarget := targetServer.Path == server.Path
+	if cfg.TargetServer != "" {
+		savedTarget = targetServer.Matches(cfg.TargetServer)
+	}
+	if !savedSource || !savedTarget {
+		savedTargets = nil
+	}
+
+	targetProfiles, err := selectTargetProfiles(targetServer.Path, selectedProfile, flagTargetProfiles, savedTargets, cfg.Profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Step 7: Create backup
-	fmt.Println("Creating backup...")
-	backupPath, err := backup.CreateBackup(selectedProfile.Path, selectedProfile.Name)
+	targetUserFiles, targetCharFiles, err := listTargetFiles(targetProfiles, details)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to create backup: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Verify backup
-	if err := backup.VerifyBackup(backupPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Backup verification failed: %v\n", err)
+	// Step 6: Select target files
+	userTargets, excludedUserIDs, err := selectTargets("user", userTargetCandidates(targetUserFiles, selectedUserFile.Path), flagIncludeUsers, flagExcludeUsers, cfg.ExcludedUserIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Backup created successfully: %s\n", backupPath)
+	charTargets, excludedCharIDs, err := selectTargets("char", characterTargetCandidates(targetCharFiles, selectedCharFile.Path), flagIncludeChars, flagExcludeChars, cfg.ExcludedCharacterIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Step 8: Perform synchronization
-	fmt.Println("Synchronizing user files...")
-	if err := sync.ReplaceUserFiles(selectedProfile.Path, selectedUserFile.Path); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to replace user files: %v\n", err)
-		fmt.Printf("You can restore from backup: %s\n", backupPath)
+	sel := &syncSelection{
+		Server:         server,
+		TargetServer:   targetServer,
+		Profile:        selectedProfile,
+		UserFile:       selectedUserFile,
+		CharacterFile:  selectedCharFile,
+		TargetProfiles: targetProfiles,
+		Options: sync.Options{
+			UserTargets:      userTargets,
+			CharacterTargets: charTargets,
+			Categories:       categories,
+		},
+		Details: details,
+	}
+
+	// Dry run: print the plan and stop before anything is written
+	if flagDryRun {
+		if err := printDryRun(sel); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Step 7: Show summary and confirm
+	if flagYes {
+		printSummary(sel)
+	} else if !confirmOperation(sel) {
+		fmt.Println("Operation cancelled.")
+		os.Exit(0)
+	}
+
+	// Step 8: Validate operation for every target profile
+	if err := sync.ValidateTargets(sel.targetPaths(), selectedUserFile.Path, selectedCharFile.Path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Validation failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Synchronizing character files...")
-	if err := sync.ReplaceCharacterFiles(selectedProfile.Path, selectedCharFile.Path); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to replace character files: %v\n", err)
-		fmt.Printf("You can restore from backup: %s\n", backupPath)
+	// Step 9: Create backup of every target profile
+	origin := backupDetails(targetServer, "sync")
+	origin.UserID = selectedUserFile.ID
+	origin.CharacterID = selectedCharFile.ID
+
+	var backupPaths []string
+	for _, target := range targetProfiles {
+		fmt.Printf("Creating backup of profile %s...\n", target.Name)
+		backupPath, err := createBackup(cfg, target.Path, target.Name, origin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to create backup: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Verify backup
+		if err := verifyBackup(cfg, backupPath, target.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Backup verification failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Backup created successfully: %s\n", backupPath)
+		replicateBackup(cfg, backupPath)
+		backupPaths = append(backupPaths, backupPath)
+	}
+	enforceRetention(cfg)
+
+	// Step 10: Perform synchronization (all-or-nothing across profiles)
+	fmt.Println("Synchronizing user and character files...")
+	if err := sync.SyncProfiles(sel.targetPaths(), selectedUserFile.Path, selectedCharFile.Path, sel.Options); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Synchronization failed: %v\n", err)
+		fmt.Println("No changes were applied to any profile.")
+		fmt.Println("Backups of the original profiles:")
+		for _, backupPath := range backupPaths {
+			fmt.Printf("  %s\n", backupPath)
+		}
 		os.Exit(1)
 	}
 
-	// Step 9: Save configuration
+	// Step 11: Save configuration
+	cfg.Server = server.DirName
 	cfg.ProfilesDir = profilesDir
 	cfg.Profile = selectedProfile.Name
 	cfg.UserID = selectedUserFile.ID
 	cfg.CharacterID = selectedCharFile.ID
+	cfg.TargetServer = targetServer.DirName
+	cfg.TargetProfiles = sel.targetNames()
+	cfg.ExcludedUserIDs = excludedUserIDs
+	cfg.ExcludedCharacterIDs = excludedCharIDs
+	if flagLogsDir != "" {
+		cfg.LogsDir = flagLogsDir
+	}
 
 	if err := config.SaveConfig(cfg); err != nil {
 		fmt.Printf("Warning: Failed to save configuration: %v\n", err)
@@ -146,50 +296,58 @@ func runSync(cmd *cobra.Command, args []string) {
 	fmt.Println("Synchronization completed successfully!")
 }
 
-func discoverProfilesDirectory(savedDir string) (string, error) {
-	// Try saved directory first
-	if savedDir != "" {
-		if err := profile.ValidateProfilesDirectory(savedDir); err == nil {
-			return savedDir, nil
-		}
+// selectWithFallback attempts to use survey.Select, but falls back to a numbered list
+// if the interactive terminal is not available (e.g., in GoLand debugger)
+func selectWithFallback(message string, options []string, defaultIndex int) (string, error) {
+	// Try interactive select first
+	prompt := &survey.Select{
+		Message: message,
+		Options: options,
+		Default: options[defaultIndex],
 	}
 
-	// Try to find default directory
-	profilesDir, err := profile.FindProfilesDirectory()
+	var selected string
+	err := survey.AskOne(prompt, &selected, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr))
+
+	// If it works, return the result
 	if err == nil {
-		return profilesDir, nil
+		return selected, nil
 	}
 
-	// Prompt user for directory
-	var userDir string
-	prompt := &survey.Input{
-		Message: "EVE profiles directory not found. Please enter the path:",
-		Help:    "Default location: C:\\Users\\{user}\\AppData\\Local\\CCP\\EVE\\c_ccp_eve_online_tq_tranquility",
+	if !isTerminalError(err) {
+		return "", fmt.Errorf("failed to select: %w", err)
 	}
 
-	if err := survey.AskOne(prompt, &userDir, survey.WithValidator(survey.Required), survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil {
-		return "", fmt.Errorf("failed to get directory from user: %w", err)
+	// Fallback to numbered list using direct stdin reading
+	input, err := askNumbered(message, options, func(i int) bool { return i == defaultIndex },
+		fmt.Sprintf("Enter number (1-%d) [default: %d]", len(options), defaultIndex+1))
+	if err != nil {
+		return "", err
 	}
 
-	// Validate user-provided directory
-	if err := profile.ValidateProfilesDirectory(userDir); err != nil {
-		return "", fmt.Errorf("invalid directory: %w", err)
+	if input == "" {
+		// Use default
+		return options[defaultIndex], nil
 	}
 
-	return userDir, nil
+	index, err := parseOptionNumber(input, len(options))
+	if err != nil {
+		return "", err
+	}
+	return options[index], nil
 }
 
-// selectWithFallback attempts to use survey.Select, but falls back to a numbered list
-// if the interactive terminal is not available (e.g., in GoLand debugger)
-func selectWithFallback(message string, options []string, defaultIndex int) (string, error) {
-	// Try interactive select first
-	prompt := &survey.Select{
+// multiSelectWithFallback attempts to use survey.MultiSelect, but falls back to a
+// numbered list if the interactive terminal is not available
+func multiSelectWithFallback(message string, options []string, defaults []string) ([]string, error) {
+	// Try interactive multi-select first
+	prompt := &survey.MultiSelect{
 		Message: message,
 		Options: options,
-		Default: options[defaultIndex],
+		Default: defaults,
 	}
 
-	var selected string
+	var selected []string
 	err := survey.AskOne(prompt, &selected, survey.WithStdio(os.Stdin, os.Stdout, os.Stderr))
 
 	// If it works, return the result
@@ -197,53 +355,100 @@ func selectWithFallback(message string, options []string, defaultIndex int) (str
 		return selected, nil
 	}
 
-	// Check if error is related to terminal incompatibility
-	errStr := err.Error()
-	if strings.Contains(errStr, "Incorrect function") ||
-		strings.Contains(errStr, "terminal") ||
-		strings.Contains(errStr, "not a terminal") {
-		// Fallback to numbered list using direct stdin reading
-		fmt.Fprintf(os.Stdout, "\n%s\n", message)
-		for i, opt := range options {
-			marker := " "
-			if i == defaultIndex {
-				marker = "*"
+	if !isTerminalError(err) {
+		return nil, fmt.Errorf("failed to select: %w", err)
+	}
+
+	// Fallback to numbered list using direct stdin reading
+	isDefault := func(i int) bool {
+		for _, d := range defaults {
+			if d == options[i] {
+				return true
 			}
-			fmt.Fprintf(os.Stdout, "  %s [%d] %s\n", marker, i+1, opt)
 		}
-		fmt.Fprintf(os.Stdout, "\nEnter number (1-%d) [default: %d]: ", len(options), defaultIndex+1)
+		return false
+	}
+	input, err := askNumbered(message, options, isDefault, `Enter numbers separated by commas, "all" or "none" [default: marked]`)
+	if err != nil {
+		return nil, err
+	}
 
-		// Read directly from stdin
-		reader := bufio.NewReader(os.Stdin)
-		input, readErr := reader.ReadString('\n')
-		if readErr != nil {
-			return "", fmt.Errorf("failed to read input: %w", readErr)
-		}
+	switch strings.ToLower(input) {
+	case "":
+		return defaults, nil
+	case "all":
+		return options, nil
+	case "none":
+		return nil, nil
+	}
 
-		// Parse input
-		input = strings.TrimSpace(input)
-		if input == "" {
-			// Use default
-			return options[defaultIndex], nil
+	var result []string
+	for _, part := range strings.Split(input, ",") {
+		index, err := parseOptionNumber(strings.TrimSpace(part), len(options))
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, options[index])
+	}
 
-		index, parseErr := strconv.Atoi(input)
-		if parseErr != nil {
-			return "", fmt.Errorf("invalid input: %s (expected a number)", input)
-		}
+	return result, nil
+}
 
-		if index < 1 || index > len(options) {
-			return "", fmt.Errorf("invalid selection: %d (must be between 1 and %d)", index, len(options))
+// isTerminalError reports whether a survey prompt failed because no
+// interactive terminal is available
+func isTerminalError(err error) bool {
+	errStr := err.Error()
+	return strings.Contains(errStr, "Incorrect function") ||
+		strings.Contains(errStr, "terminal") ||
+		strings.Contains(errStr, "not a terminal")
+}
+
+// askNumbered is the prompt fallback without a terminal: it prints the
+// options as a numbered list, marking the defaults, and reads the answer
+// from stdin
+func askNumbered(message string, options []string, isDefault func(int) bool, hint string) (string, error) {
+	fmt.Fprintf(os.Stdout, "\n%s\n", message)
+	for i, opt := range options {
+		marker := " "
+		if isDefault(i) {
+			marker = "*"
 		}
+		fmt.Fprintf(os.Stdout, "  %s [%d] %s\n", marker, i+1, opt)
+	}
+	fmt.Fprintf(os.Stdout, "\n%s: ", hint)
+
+	input, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// parseOptionNumber converts a 1-based option number to an index
+func parseOptionNumber(input string, count int) (int, error) {
+	index, err := strconv.Atoi(input)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input: %s (expected a number)", input)
+	}
 
-		return options[index-1], nil
+	if index < 1 || index > count {
+		return 0, fmt.Errorf("invalid selection: %d (must be between 1 and %d)", index, count)
 	}
 
-	// If it's a different error, return it
-	return "", fmt.Errorf("failed to select: %w", err)
+	return index - 1, nil
+}
+
+// readLine reads a line directly from stdin, without the line ending
+func readLine() (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimRight(input, "\r\n"), nil
 }
 
-func selectProfile(profilesDir, savedProfile string) (*profile.Profile, error) {
+func selectProfile(profilesDir, flagProfile, savedProfile string) (*profile.Profile, error) {
 	profiles, err := profile.ListProfiles(profilesDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list profiles: %w", err)
@@ -253,6 +458,25 @@ func selectProfile(profilesDir, savedProfile string) (*profile.Profile, error) {
 		return nil, fmt.Errorf("no profiles found in directory: %s", profilesDir)
 	}
 
+	// Resolve without prompting when requested
+	if flagProfile != "" || nonInteractive {
+		name, err := resolveValue("profile", flagProfile, savedProfile, len(profiles) == 1, profiles[0].Name)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimPrefix(name, "settings_")
+		for _, p := range profiles {
+			if p.Name == name {
+				return &p, nil
+			}
+		}
+		names := make([]string, len(profiles))
+		for i, p := range profiles {
+			names[i] = p.Name
+		}
+		return nil, fmt.Errorf("profile %q not found in %s (available: %s)", name, profilesDir, strings.Join(names, ", "))
+	}
+
 	// Build options for survey
 	options := make([]string, len(profiles))
 	defaultIndex := 0
@@ -278,18 +502,28 @@ func selectProfile(profilesDir, savedProfile string) (*profile.Profile, error) {
 	return nil, fmt.Errorf("selected profile not found")
 }
 
-func selectUserFile(userFiles []profile.UserFile, savedUserID string) (*profile.UserFile, error) {
+func selectUserFile(userFiles []profile.UserFile, flagUserID, savedUserID string) (*profile.UserFile, error) {
+	// Resolve without prompting when requested
+	if flagUserID != "" || nonInteractive {
+		id, err := resolveValue("user", flagUserID, savedUserID, len(userFiles) == 1, userFiles[0].ID)
+		if err != nil {
+			return nil, err
+		}
+		for i, uf := range userFiles {
+			if uf.ID == id {
+				return &userFiles[i], nil
+			}
+		}
+		return nil, fmt.Errorf("user file core_user_%s.dat not found in profile", id)
+	}
+
 	// Build options for survey
 	options := make([]string, len(userFiles))
 	descriptions := make([]string, len(userFiles))
 	defaultIndex := 0
 
 	for i, uf := range userFiles {
-		display := fmt.Sprintf("User ID: %s", uf.ID)
-		if uf.Name != "" {
-			display = fmt.Sprintf("%s (Name: %s)", display, uf.Name)
-		}
-		options[i] = display
+		options[i] = uf.Label()
 		descriptions[i] = uf.Path
 
 		if savedUserID != "" && uf.ID == savedUserID {
@@ -312,17 +546,27 @@ func selectUserFile(userFiles []profile.UserFile, savedUserID string) (*profile.
 	return nil, fmt.Errorf("selected user file not found")
 }
 
-func selectCharacterFile(charFiles []profile.CharacterFile, savedCharID string) (*profile.CharacterFile, error) {
+func selectCharacterFile(charFiles []profile.CharacterFile, flagCharID, savedCharID string) (*profile.CharacterFile, error) {
+	// Resolve without prompting when requested
+	if flagCharID != "" || nonInteractive {
+		id, err := resolveValue("char", flagCharID, savedCharID, len(charFiles) == 1, charFiles[0].ID)
+		if err != nil {
+			return nil, err
+		}
+		for i, cf := range charFiles {
+			if cf.ID == id {
+				return &charFiles[i], nil
+			}
+		}
+		return nil, fmt.Errorf("character file core_char_%s.dat not found in profile", id)
+	}
+
 	// Build options for survey
 	options := make([]string, len(charFiles))
 	defaultIndex := 0
 
 	for i, cf := range charFiles {
-		display := fmt.Sprintf("Character ID: %s", cf.ID)
-		if cf.Name != "" {
-			display = fmt.Sprintf("%s (Name: %s)", display, cf.Name)
-		}
-		options[i] = display
+		options[i] = cf.Label()
 
 		if savedCharID != "" && cf.ID == savedCharID {
 			defaultIndex = i
@@ -344,20 +588,93 @@ func selectCharacterFile(charFiles []profile.CharacterFile, savedCharID string)
 	return nil, fmt.Errorf("selected character file not found")
 }
 
-func confirmOperation(selectedProfile *profile.Profile, selectedUserFile *profile.UserFile, selectedCharFile *profile.CharacterFile) bool {
-	summary := fmt.Sprintf(`Operation Summary:
+// resolveValue picks a value for non-interactive mode: the flag value wins,
+// then the saved config value, then the only available candidate
+func resolveValue(flagName, flagValue, savedValue string, single bool, singleValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if savedValue != "" {
+		return savedValue, nil
+	}
+	if single {
+		return singleValue, nil
+	}
+	return "", fmt.Errorf("cannot resolve %s non-interactively: pass --%s", flagName, flagName)
+}
+
+// syncSelection holds everything chosen for a synchronization run
+type syncSelection struct {
+	Server         *profile.Server
+	TargetServer   *profile.Server
+	Profile        *profile.Profile // Source profile
+	UserFile       *profile.UserFile
+	CharacterFile  *profile.CharacterFile
+	TargetProfiles []profile.Profile
+	Options        sync.Options
+	Details        *fileDetails
+}
+
+// targetPaths returns the directories of all target profiles
+func (s *syncSelection) targetPaths() []string {
+	paths := make([]string, len(s.TargetProfiles))
+	for i, p := range s.TargetProfiles {
+		paths[i] = p.Path
+	}
+	return paths
+}
+
+// targetNames returns the names of all target profiles
+func (s *syncSelection) targetNames() []string {
+	names := make([]string, len(s.TargetProfiles))
+	for i, p := range s.TargetProfiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func operationSummary(sel *syncSelection) string {
+	return fmt.Sprintf(`Operation Summary:
+  Server: %s
   Profile: %s
   User ID: %s
   Character ID: %s
+  Target server: %s
+  Target profiles: %s
+  User targets: %s
+  Character targets: %s
+  Settings: %s
+
+This will %s.
+A backup of every target profile will be created before making any changes.`, sel.Server.Name, sel.Profile.Name, sel.UserFile.Describe(), sel.CharacterFile.Describe(),
+		sel.TargetServer.Name, strings.Join(sel.targetNames(), ", "),
+		describeTargets(sel.Options.UserTargets, sel.Details.userAliases), describeTargets(sel.Options.CharacterTargets, sel.Details.characterAliases),
+		sel.Options.Categories, describeOperation(sel.Options.Categories))
+}
 
-This will replace all user and character files in the profile with the selected ones.
-A backup will be created before making any changes.
+// describeOperation explains what happens to the target files
+func describeOperation(categories settings.Selection) string {
+	if categories.IsEmpty() {
+		return "replace the targeted user and character files in the target profiles with the selected ones"
+	}
+	return "copy " + categories.String() + " settings from the selected files into the targeted files, keeping everything else"
+}
 
-Proceed?`, selectedProfile.Name, selectedUserFile.ID, selectedCharFile.ID)
+// printSummary prints the operation summary without asking for confirmation
+func printSummary(sel *syncSelection) {
+	fmt.Println(operationSummary(sel))
+	fmt.Println()
+}
+
+func confirmOperation(sel *syncSelection) bool {
+	return askConfirm(operationSummary(sel) + "\n\nProceed?")
+}
 
+// askConfirm asks a yes/no question, defaulting to no
+func askConfirm(message string) bool {
 	var proceed bool
 	prompt := &survey.Confirm{
-		Message: summary,
+		Message: message,
 		Default: false,
 	}
 
@@ -367,3 +684,46 @@ Proceed?`, selectedProfile.Name, selectedUserFile.ID, selectedCharFile.ID)
 
 	return proceed
 }
+
+// printDryRun prints the replacement plans for the selected files without writing anything
+func printDryRun(sel *syncSelection) error {
+	fmt.Printf("Dry run for source profile %s (no files will be changed)\n", sel.Profile.Name)
+
+	for _, target := range sel.TargetProfiles {
+		userPlan, charPlan, err := sync.PlanProfile(target.Path, sel.UserFile.Path, sel.CharacterFile.Path, sel.Options)
+		if err != nil {
+			return fmt.Errorf("failed to plan profile %s: %w", target.Name, err)
+		}
+
+		fmt.Printf("\n=== Target profile %s ===\n\n", target.Name)
+		printPlan(userPlan, sel.Details)
+		fmt.Println()
+		printPlan(charPlan, sel.Details)
+	}
+
+	return nil
+}
+
+// printPlan prints a replacement plan as a table. With details, the registered
+// alias of each user and character file is shown next to its name.
+func printPlan(plan *sync.Plan, details *fileDetails) {
+	fmt.Printf("%s files (source: %s, %d bytes):\n", strings.ToUpper(plan.Kind[:1])+plan.Kind[1:], filepath.Base(plan.Source), plan.SourceSize)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ACTION\tFILE\tALIAS\tSIZE\tMODIFIED\tNOTE")
+	for _, e := range plan.Entries {
+		modified := "-"
+		if !e.ModTime.IsZero() {
+			modified = e.ModTime.Format("2006-01-02 15:04:05")
+		}
+		alias := details.fileAlias(e.Name)
+		if alias == "" {
+			alias = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\t%s\n", e.Action, e.Name, alias, e.Size, modified, e.Reason)
+	}
+	w.Flush()
+
+	fmt.Printf("  %d to replace, %d unchanged, %d skipped\n",
+		plan.Count(sync.ActionReplace), plan.Count(sync.ActionUnchanged), plan.Count(sync.ActionSkip))
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eve-profile-sync/internal/config"
+	"eve-profile-sync/internal/profile"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// discoverServer resolves the server directory to work with, together with
+// every server found next to it. An explicit --profiles-dir wins, then
+// --server, then the saved directory, even if discovery does not find it;
+// when several servers exist the user picks one.
+func discoverServer(flagDir, flagServer string, cfg *config.Config) (*profile.Server, []profile.Server, error) {
+	// Directory passed on the command line must be valid, no fallback
+	if flagDir != "" {
+		if err := profile.ValidateProfilesDirectory(flagDir); err != nil {
+			return nil, nil, fmt.Errorf("invalid --profiles-dir: %w", err)
+		}
+		server := profile.ServerFromPath(flagDir)
+		return &server, []profile.Server{server}, nil
+	}
+
+	// Discover every server directory under CCP\EVE
+	servers, discoverErr := discoverServers()
+
+	// A saved directory that discovery does not know, e.g. a custom
+	// profiles_dir, is used as long as it is valid
+	savedDir := cfg.ProfilesDir != "" && profile.ValidateProfilesDirectory(cfg.ProfilesDir) == nil
+	if flagServer == "" && savedDir {
+		if _, err := profile.FindServer(servers, cfg.ProfilesDir); err != nil {
+			server := profile.ServerFromPath(cfg.ProfilesDir)
+			return &server, append([]profile.Server{server}, servers...), nil
+		}
+	}
+
+	if discoverErr == nil && len(servers) > 0 {
+		// Prefer the saved directory: the same server may exist in several
+		// installations (Windows, Proton, Wine)
+		savedServer := cfg.Server
+		if savedDir {
+			savedServer = cfg.ProfilesDir
+		}
+
+		server, err := selectServer(servers, "Select server:", "server", flagServer, savedServer)
+		if err != nil {
+			return nil, nil, err
+		}
+		return server, servers, nil
+	}
+
+	if flagServer != "" {
+		if discoverErr != nil {
+			return nil, nil, fmt.Errorf("cannot resolve --server: %w", discoverErr)
+		}
+		return nil, nil, fmt.Errorf("cannot resolve --server: no EVE server directories found (use --profiles-dir)")
+	}
+
+	if nonInteractive {
+		return nil, nil, fmt.Errorf("EVE profiles directory not found (use --profiles-dir)")
+	}
+
+	// Prompt user for directory
+	var userDir string
+	prompt := &survey.Input{
+		Message: "EVE profiles directory not found. Please enter the path:",
+		Help:    "Default location: C:\\Users\\{user}\\AppData\\Local\\CCP\\EVE\\c_ccp_eve_online_tq_tranquility (inside the Wine/Proton prefix on Linux)",
+	}
+
+	if err := survey.AskOne(prompt, &userDir, survey.WithValidator(survey.Required), survey.WithStdio(os.Stdin, os.Stdout, os.Stderr)); err != nil {
+		return nil, nil, fmt.Errorf("failed to get directory from user: %w", err)
+	}
+
+	// Validate user-provided directory
+	if err := profile.ValidateProfilesDirectory(userDir); err != nil {
+		return nil, nil, fmt.Errorf("invalid directory: %w", err)
+	}
+
+	server := profile.ServerFromPath(userDir)
+	return &server, []profile.Server{server}, nil
+}
+
+// discoverServers lists all server directories of every EVE installation
+func discoverServers() ([]profile.Server, error) {
+	return profile.DiscoverServers()
+}
+
+// selectServer picks a server: the flag value wins, then a single candidate,
+// then the saved server in non-interactive mode; otherwise the user is asked
+func selectServer(servers []profile.Server, message, flagName, flagServer, savedServer string) (*profile.Server, error) {
+	if flagServer != "" {
+		return profile.FindServer(servers, flagServer)
+	}
+
+	if len(servers) == 1 {
+		return &servers[0], nil
+	}
+
+	if nonInteractive {
+		if savedServer != "" {
+			if server, err := profile.FindServer(servers, savedServer); err == nil {
+				return server, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot resolve %s non-interactively: pass --%s", flagName, flagName)
+	}
+
+	// Build options for survey
+	options := make([]string, len(servers))
+	defaultIndex := 0
+	for i, s := range servers {
+		options[i] = s.Label()
+		if savedServer != "" && s.Matches(savedServer) {
+			defaultIndex = i
+		}
+	}
+
+	selected, err := selectWithFallback(message, options, defaultIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select server: %w", err)
+	}
+
+	for i, opt := range options {
+		if opt == selected {
+			return &servers[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("selected server not found")
+}
+
+// selectTargetServer picks the server whose profiles receive the source
+// settings. It defaults to the source server; the saved target server is
+// offered instead only when it was saved for the same source server
+// (savedSource), and never used without asking: with --yes, another server
+// must be passed as --target-server. Servers of the source installation take
+// precedence when a name matches in several installations.
+func selectTargetServer(servers []profile.Server, source *profile.Server, flagServer, savedServer string, savedSource bool) (*profile.Server, error) {
+	if flagServer == "" && nonInteractive {
+		return source, nil
+	}
+	if !savedSource || savedServer == "" {
+		savedServer = source.Path
+	}
+
+	ordered := make([]profile.Server, 0, len(servers))
+	for _, s := range servers {
+		if s.Location == source.Location {
+			ordered = append(ordered, s)
+		}
+	}
+	for _, s := range servers {
+		if s.Location != source.Location {
+			ordered = append(ordered, s)
+		}
+	}
+	servers = ordered
+
+	return selectServer(servers, "Select target server:", "target-server", flagServer, savedServer)
+}
+
+// isSavedSource reports whether the source server is the one saved in the
+// config, to which the saved target server and profiles belong
+func isSavedSource(source *profile.Server, cfg *config.Config) bool {
+	if cfg.ProfilesDir != "" {
+		return filepath.Clean(cfg.ProfilesDir) == filepath.Clean(source.Path)
+	}
+	return cfg.Server != "" && source.Matches(cfg.Server)
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eve-profile-sync/internal/backup"
+	"eve-profile-sync/internal/config"
+)
+
+// localTarget is the name of the backup directory when used as a target
+const localTarget = "local"
+
+// backupTargets builds the replication targets configured in backup_targets
+func backupTargets(cfg *config.Config) ([]backup.Target, error) {
+	var targets []backup.Target
+	seen := map[string]bool{localTarget: true}
+	for _, tc := range cfg.BackupTargets {
+		if tc.Name == "" {
+			return nil, fmt.Errorf("backup target without a name in backup_targets")
+		}
+		if seen[tc.Name] {
+			return nil, fmt.Errorf("backup target name %q is used twice or reserved", tc.Name)
+		}
+		seen[tc.Name] = true
+
+		switch tc.Type {
+		case "dir":
+			if tc.Path == "" {
+				return nil, fmt.Errorf("backup target %s needs a path", tc.Name)
+			}
+			targets = append(targets, backup.NewDirTarget(tc.Name, tc.Path))
+		case "s3":
+			accessKey, secretKey := tc.AccessKeyID, tc.SecretAccessKey
+			if accessKey == "" {
+				accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+			}
+			if secretKey == "" {
+				secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			}
+
+			target, err := backup.NewS3Target(tc.Name, backup.S3Config{
+				Endpoint:        tc.Endpoint,
+				Region:          tc.Region,
+				Bucket:          tc.Bucket,
+				Prefix:          tc.Prefix,
+				AccessKeyID:     accessKey,
+				SecretAccessKey: secretKey,
+			})
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+		default:
+			return nil, fmt.Errorf("invalid type %q for backup target %s (expected dir or s3)", tc.Type, tc.Name)
+		}
+	}
+	return targets, nil
+}
+
+// findTarget returns the backup target with the given name; "local" is the
+// backup directory
+func findTarget(cfg *config.Config, name string) (backup.Target, error) {
+	if name == localTarget {
+		return backup.NewDirTarget(localTarget, backupRoot(cfg)), nil
+	}
+
+	targets, err := backupTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		if t.Name() == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("backup target %q not found in backup_targets", name)
+}
+
+// replicateBackup copies a new, verified backup to every backup target.
+// Failures only produce a warning: the local backup is already safe.
+func replicateBackup(cfg *config.Config, backupPath string) {
+	targets, err := backupTargets(cfg)
+	if err != nil {
+		fmt.Printf("Warning: Backup was not replicated: %v\n", err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if filepath.Ext(backupPath) == ".json" {
+		fmt.Println("Warning: Snapshots are not replicated to backup targets; use backup export to copy them")
+		return
+	}
+
+	if err := backup.Replicate(backupRoot(cfg), backupPath, targets); err != nil {
+		fmt.Printf("Warning: Failed to replicate backup: %v\n", err)
+		return
+	}
+	fmt.Printf("Backup replicated to %d targets\n", len(targets))
+}
+
+// pruneTargets applies the retention policy to every backup target. A
+// target that fails is reported and the others are still pruned.
+func pruneTargets(cfg *config.Config, policy backup.Policy) []backup.Archive {
+	targets, err := backupTargets(cfg)
+	if err != nil {
+		fmt.Printf("Warning: Backup targets were not pruned: %v\n", err)
+		return nil
+	}
+
+	var removed []backup.Archive
+	for _, t := range targets {
+		pruned, err := backup.PruneTarget(t, policy)
+		removed = append(removed, pruned...)
+		if err != nil {
+			fmt.Printf("Warning: Failed to prune backups on %s: %v\n", t.Name(), err)
+		}
+	}
+	return removed
+}
+
+// fetchBackup downloads a backup from its target into a new temp directory
+// and points the archive at the local copy. The returned function removes it.
+func fetchBackup(cfg *config.Config, a *backup.Archive) (func(), error) {
+	t, err := findTarget(cfg, a.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "eve-profile-sync-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	localPath, err := backup.Fetch(t, a.Path, dir)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	a.Path = localPath
+	return cleanup, nil
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"eve-profile-sync/internal/profile"
+	"eve-profile-sync/internal/sync"
+)
+
+// targetCandidate is a file that can receive the source content
+type targetCandidate struct {
+	ID    string
+	Label string
+}
+
+// selectTargetProfiles resolves which profiles receive the source settings.
+// The --target-profile flag wins; in non-interactive mode the default targets
+// are used; otherwise the user picks them from a multi-select list. The
+// saved target profiles are the default only when they were saved for the
+// same source profile (savedSource); otherwise the source profile is.
+func selectTargetProfiles(profilesDir string, source *profile.Profile, flagTargets, savedTargets []string, savedSource string) ([]profile.Profile, error) {
+	profiles, err := profile.ListProfiles(profilesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	byName := make(map[string]profile.Profile, len(profiles))
+	options := make([]string, len(profiles))
+	for i, p := range profiles {
+		byName[p.Name] = p
+		options[i] = p.Name
+	}
+
+	// Saved targets that no longer exist are dropped silently
+	var defaults []string
+	if savedSource == source.Name {
+		for _, name := range savedTargets {
+			if _, ok := byName[name]; ok {
+				defaults = append(defaults, name)
+			}
+		}
+	}
+	if _, ok := byName[source.Name]; ok && len(defaults) == 0 {
+		defaults = []string{source.Name}
+	}
+
+	var names []string
+	switch {
+	case len(flagTargets) > 0:
+		names = flagTargets
+	case nonInteractive || len(profiles) == 1:
+		names = defaults
+	default:
+		selected, err := multiSelectWithFallback("Select target profiles to receive the source settings:", options, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select target profiles: %w", err)
+		}
+		names = selected
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no target profiles selected (pass --target-profile)")
+	}
+
+	// A profile named twice, e.g. as PvP and settings_PvP, is a target once:
+	// its files must not enter the transaction twice
+	var targets []profile.Profile
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "settings_")
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("target profile %q not found in %s (available: %s)", name, profilesDir, strings.Join(options, ", "))
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		targets = append(targets, p)
+	}
+
+	return targets, nil
+}
+
+// listTargetFiles lists user and character files across all target profiles,
+// with character names resolved from the name sources
+func listTargetFiles(targets []profile.Profile, details *fileDetails) ([]profile.UserFile, []profile.CharacterFile, error) {
+	var userFiles []profile.UserFile
+	var charFiles []profile.CharacterFile
+
+	for _, target := range targets {
+		uf, err := profile.ListUserFiles(target.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list user files in profile %s: %w", target.Name, err)
+		}
+		userFiles = append(userFiles, uf...)
+
+		cf, err := profile.ListCharacterFiles(target.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list character files in profile %s: %w", target.Name, err)
+		}
+		charFiles = append(charFiles, cf...)
+	}
+
+	details.userFiles(userFiles)
+	details.characterFiles(charFiles)
+
+	return userFiles, charFiles, nil
+}
+
+// userTargetCandidates returns every user file except the source one, once per ID
+func userTargetCandidates(userFiles []profile.UserFile, sourcePath string) []targetCandidate {
+	var candidates []targetCandidate
+	seen := make(map[string]bool)
+	for _, uf := range userFiles {
+		if uf.Path == sourcePath || seen[uf.ID] {
+			continue
+		}
+		seen[uf.ID] = true
+		candidates = append(candidates, targetCandidate{ID: uf.ID, Label: uf.Label()})
+	}
+	return candidates
+}
+
+// characterTargetCandidates returns every character file except the source one, once per ID
+func characterTargetCandidates(charFiles []profile.CharacterFile, sourcePath string) []targetCandidate {
+	var candidates []targetCandidate
+	seen := make(map[string]bool)
+	for _, cf := range charFiles {
+		if cf.Path == sourcePath || seen[cf.ID] {
+			continue
+		}
+		seen[cf.ID] = true
+		candidates = append(candidates, targetCandidate{ID: cf.ID, Label: cf.Label()})
+	}
+	return candidates
+}
+
+// selectTargets resolves which files of one kind receive the source content.
+// Include/exclude flags win; in non-interactive mode the saved exclusions are
+// used; otherwise the user picks targets from a multi-select list. It returns
+// the filter and the exclusion list to remember in config.
+func selectTargets(kind string, candidates []targetCandidate, include, exclude, savedExcluded []string) (sync.TargetFilter, []string, error) {
+	var filter sync.TargetFilter
+
+	switch {
+	case len(include) > 0 || len(exclude) > 0:
+		filter = sync.TargetFilter{Include: include, Exclude: exclude}
+	case nonInteractive || len(candidates) == 0:
+		filter = sync.TargetFilter{Exclude: savedExcluded}
+	default:
+		options := make([]string, len(candidates))
+		var defaults []string
+		for i, c := range candidates {
+			options[i] = c.Label
+			if !containsID(savedExcluded, c.ID) {
+				defaults = append(defaults, c.Label)
+			}
+		}
+
+		selected, err := multiSelectWithFallback(fmt.Sprintf("Select %s files to receive the source settings:", targetKindName(kind)), options, defaults)
+		if err != nil {
+			return sync.TargetFilter{}, nil, fmt.Errorf("failed to select %s targets: %w", targetKindName(kind), err)
+		}
+
+		var excluded []string
+		for _, c := range candidates {
+			if !containsID(selected, c.Label) {
+				excluded = append(excluded, c.ID)
+			}
+		}
+		filter = sync.TargetFilter{Exclude: excluded}
+	}
+
+	return filter, rememberedExclusions(filter, candidates, savedExcluded), nil
+}
+
+// rememberedExclusions lists the candidates the filter deselects, keeping saved
+// exclusions for IDs that are not present in the current profile
+func rememberedExclusions(filter sync.TargetFilter, candidates []targetCandidate, savedExcluded []string) []string {
+	excluded := []string{}
+	present := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		present[c.ID] = true
+		if ok, _ := filter.Allows(c.ID); !ok {
+			excluded = append(excluded, c.ID)
+		}
+	}
+
+	for _, id := range savedExcluded {
+		if !present[id] && !containsID(excluded, id) {
+			excluded = append(excluded, id)
+		}
+	}
+
+	return excluded
+}
+
+// describeTargets renders a target filter for the operation summary, adding
+// the registered alias after each ID
+func describeTargets(filter sync.TargetFilter, aliases map[string]string) string {
+	switch {
+	case filter.IsEmpty():
+		return "all files"
+	case len(filter.Include) > 0 && len(filter.Exclude) > 0:
+		return fmt.Sprintf("only IDs %s, excluding %s", describeIDs(filter.Include, aliases), describeIDs(filter.Exclude, aliases))
+	case len(filter.Include) > 0:
+		return "only IDs " + describeIDs(filter.Include, aliases)
+	default:
+		return "all except IDs " + describeIDs(filter.Exclude, aliases)
+	}
+}
+
+func describeIDs(ids []string, aliases map[string]string) string {
+	described := make([]string, len(ids))
+	for i, id := range ids {
+		described[i] = id
+		if alias := aliases[id]; alias != "" {
+			described[i] = fmt.Sprintf("%s (%s)", id, alias)
+		}
+	}
+	return strings.Join(described, ", ")
+}
+
+func targetKindName(kind string) string {
+	if kind == "char" {
+		return "character"
+	}
+	return kind
+}
+
+func containsID(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
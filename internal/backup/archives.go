@@ -0,0 +1,287 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LegacyDir is the directory, relative to the working directory, that older
+// versions wrote all backups to
+const LegacyDir = "backup"
+
+// UnknownServer is the server folder of backups whose server is not known,
+// such as migrated backups made before manifests existed
+const UnknownServer = "unknown-server"
+
+// DefaultDir returns the backups directory in the per-user data directory:
+// %LOCALAPPDATA% on Windows, ~/Library/Application Support on macOS and
+// $XDG_DATA_HOME or ~/.local/share elsewhere
+func DefaultDir() string {
+	var dataDir string
+	switch runtime.GOOS {
+	case "windows":
+		dataDir = os.Getenv("LOCALAPPDATA")
+	case "darwin":
+		if home, err := os.UserHomeDir(); err == nil {
+			dataDir = filepath.Join(home, "Library", "Application Support")
+		}
+	default:
+		dataDir = os.Getenv("XDG_DATA_HOME")
+		if dataDir == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				dataDir = filepath.Join(home, ".local", "share")
+			}
+		}
+	}
+
+	if dataDir == "" {
+		return LegacyDir
+	}
+	return filepath.Join(dataDir, "eve-profile-sync", "backups")
+}
+
+// ProfileDir returns the folder for the backups of a profile on a server:
+// {root}/{server directory}/settings_{Profile}
+func ProfileDir(root, server, profileName string) string {
+	if server == "" {
+		server = UnknownServer
+	}
+	return filepath.Join(root, server, "settings_"+profileName)
+}
+
+// timestampFormat is the timestamp part of backup filenames
+const timestampFormat = "20060102-150405"
+
+// legacyTimestampFormat is the minute-resolution timestamp of older backups
+const legacyTimestampFormat = "20060102-1504"
+
+// archiveNameRegex matches backup names without their extension:
+// settings_{Profile}_{timestamp}[-{sequence}]
+var archiveNameRegex = regexp.MustCompile(`^settings_(.+)_(\d{8}-\d{6}|\d{8}-\d{4})(?:-(\d+))?$`)
+
+// archiveName returns the name of a backup without extension. The first
+// backup of a second has no sequence suffix; later ones are numbered from 2.
+func archiveName(profileName string, taken time.Time, seq int) string {
+	name := fmt.Sprintf("settings_%s_%s", profileName, taken.Format(timestampFormat))
+	if seq > 1 {
+		name += fmt.Sprintf("-%d", seq)
+	}
+	return name
+}
+
+// Archive is a backup found in the backup directory: a zip file or a snapshot
+// in the snapshot store
+type Archive struct {
+	Path      string
+	Server    string    // Server folder; empty for backups directly in the backup directory
+	Profile   string    // Profile name without the settings_ prefix
+	Time      time.Time // When the backup was taken, from the filename
+	Sequence  int       // Position among backups taken in the same second
+	Size      int64     // File size; for snapshots the total size of their files
+	Snapshot  bool
+	Encrypted bool
+	Target    string // Backup target the backup is stored on; empty for the backup directory
+}
+
+// Name returns the backup name, the filename without extension
+func (a Archive) Name() string {
+	base := filepath.Base(a.Path)
+	if IsEncrypted(base) {
+		return strings.TrimSuffix(base, EncryptedExt)
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// parseArchiveName reads the profile, time and sequence from a backup name
+// without extension
+func parseArchiveName(name string) (Archive, bool) {
+	matches := archiveNameRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return Archive{}, false
+	}
+
+	layout := timestampFormat
+	if len(matches[2]) == len(legacyTimestampFormat) {
+		layout = legacyTimestampFormat
+	}
+	taken, err := time.ParseInLocation(layout, matches[2], time.Local)
+	if err != nil {
+		return Archive{}, false
+	}
+
+	seq := 1
+	if matches[3] != "" {
+		seq, _ = strconv.Atoi(matches[3])
+	}
+
+	return Archive{Profile: matches[1], Time: taken, Sequence: seq}, true
+}
+
+// ListArchives returns the zip backups, plain and encrypted, in the profile
+// folders of root and directly in root, where older versions put them. The
+// result is sorted newest first. Files that are not named like backups are
+// ignored; a missing directory has no backups.
+func ListArchives(root string) ([]Archive, error) {
+	archives, err := listArchiveDir(root, "")
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := subdirs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		if server == storeDir {
+			continue
+		}
+
+		profiles, err := subdirs(filepath.Join(root, server))
+		if err != nil {
+			return nil, err
+		}
+		for _, profileDir := range profiles {
+			if !strings.HasPrefix(profileDir, "settings_") {
+				continue
+			}
+
+			found, err := listArchiveDir(filepath.Join(root, server, profileDir), server)
+			if err != nil {
+				return nil, err
+			}
+			archives = append(archives, found...)
+		}
+	}
+
+	SortArchives(archives)
+	return archives, nil
+}
+
+// subdirs returns the names of the directories in dir
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// listArchiveDir returns the zip backups directly in dir
+func listArchiveDir(dir, server string) ([]Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		var name string
+		encrypted := IsEncrypted(entry.Name())
+		switch {
+		case encrypted:
+			name = strings.TrimSuffix(entry.Name(), EncryptedExt)
+		case filepath.Ext(entry.Name()) == ".zip":
+			name = strings.TrimSuffix(entry.Name(), ".zip")
+		default:
+			continue
+		}
+
+		archive, ok := parseArchiveName(name)
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat backup %s: %w", entry.Name(), err)
+		}
+
+		archive.Path = filepath.Join(dir, entry.Name())
+		archive.Server = server
+		archive.Size = info.Size()
+		archive.Encrypted = encrypted
+		archives = append(archives, archive)
+	}
+
+	return archives, nil
+}
+
+// SortArchives orders backups newest first
+func SortArchives(archives []Archive) {
+	sort.SliceStable(archives, func(i, j int) bool {
+		if !archives[i].Time.Equal(archives[j].Time) {
+			return archives[i].Time.After(archives[j].Time)
+		}
+		return archives[i].Sequence > archives[j].Sequence
+	})
+}
+
+// sizeUnits are the suffixes accepted by ParseSize, largest first
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "500MB", "2 GB" or "1048576". Units are
+// binary (1KB = 1024 bytes). An empty string is 0.
+func ParseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	if text == "" {
+		return 0, nil
+	}
+
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 500MB)", s)
+	}
+
+	return int64(value * float64(factor)), nil
+}
+
+// FormatSize renders a byte count with a binary unit, e.g. "1.5 MB"
+func FormatSize(n int64) string {
+	for _, unit := range sizeUnits[:3] {
+		if n >= unit.factor {
+			return fmt.Sprintf("%.1f %s", float64(n)/float64(unit.factor), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -2,38 +2,87 @@ package backup
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
 )
 
-// CreateBackup creates a zip backup of the profile directory
-func CreateBackup(profilePath, profileName string) (string, error) {
+// CreateBackup creates a zip backup of the profile directory in the profile's
+// folder under root. A manifest with the details and the SHA-256 of every
+// file is stored alongside the files.
+// The archive is written to a temp file and only gets its final name once it
+// is complete, and an existing backup is never overwritten. With a passphrase
+// the archive is encrypted and named with EncryptedExt.
+func CreateBackup(root, profilePath, profileName string, details Details, passphrase string) (string, error) {
 	// Create backup directory if it doesn't exist
-	backupDir := "backup"
+	backupDir := ProfileDir(root, details.Server, profileName)
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Generate backup filename with timestamp
-	timestamp := time.Now().Format("20060102-1504")
-	backupFilename := fmt.Sprintf("settings_%s_%s.zip", profileName, timestamp)
-	backupPath := filepath.Join(backupDir, backupFilename)
+	manifest := newManifest(profilePath, profileName, details)
 
-	// Create zip file
-	zipFile, err := os.Create(backupPath)
+	ext := ".zip"
+	if passphrase != "" {
+		ext = EncryptedExt
+	}
+
+	// Write the archive under a temp name that ListArchives ignores
+	zipFile, err := os.CreateTemp(backupDir, ".settings_"+profileName+"_*"+ext+".tmp")
 	if err != nil {
 		return "", fmt.Errorf("failed to create backup file: %w", err)
 	}
-	defer zipFile.Close()
+	tempPath := zipFile.Name()
+	published := false
+	defer func() {
+		if !published {
+			os.Remove(tempPath)
+		}
+	}()
+
+	if passphrase == "" {
+		err = writeArchive(zipFile, profilePath, manifest)
+	} else {
+		err = writeEncryptedArchive(zipFile, profilePath, manifest, passphrase)
+	}
+	if err != nil {
+		zipFile.Close()
+		return "", err
+	}
+
+	if err := zipFile.Sync(); err != nil {
+		zipFile.Close()
+		return "", fmt.Errorf("failed to flush backup: %w", err)
+	}
+
+	if err := zipFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize backup: %w", err)
+	}
+
+	backupPath, err := publishArchive(tempPath, backupDir, profileName, manifest.Created, ext)
+	if err != nil {
+		return "", err
+	}
+	published = true
+
+	return backupPath, nil
+}
 
-	zipWriter := zip.NewWriter(zipFile)
+// writeArchive writes every file of the profile directory and the manifest as
+// a zip archive
+func writeArchive(w io.Writer, profilePath string, manifest *Manifest) error {
+	zipWriter := zip.NewWriter(w)
 	defer zipWriter.Close()
 
 	// Walk through profile directory and add files to zip
-	err = filepath.Walk(profilePath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(profilePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -55,7 +104,7 @@ func CreateBackup(profilePath, profileName string) (string, error) {
 			return err
 		}
 
-		header.Name = relPath
+		header.Name = filepath.ToSlash(relPath)
 		header.Method = zip.Deflate
 
 		// Create writer for file
@@ -71,25 +120,104 @@ func CreateBackup(profilePath, profileName string) (string, error) {
 		}
 		defer sourceFile.Close()
 
-		// Copy file content to zip
-		_, err = io.Copy(writer, sourceFile)
-		return err
+		// Copy file content to zip, hashing it on the way
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(writer, hash), sourceFile)
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Name:     header.Name,
+			Size:     size,
+			SHA256:   hex.EncodeToString(hash.Sum(nil)),
+			Modified: info.ModTime(),
+		})
+		return nil
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to create backup: %w", err)
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if err := writeManifest(zipWriter, manifest); err != nil {
+		return err
 	}
 
 	// Close zip writer to finalize
 	if err := zipWriter.Close(); err != nil {
-		return "", fmt.Errorf("failed to finalize backup: %w", err)
+		return fmt.Errorf("failed to finalize backup: %w", err)
 	}
 
-	return backupPath, nil
+	return nil
+}
+
+// writeEncryptedArchive builds the zip archive in memory and writes it
+// encrypted with the passphrase
+func writeEncryptedArchive(w io.Writer, profilePath string, manifest *Manifest, passphrase string) error {
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, profilePath, manifest); err != nil {
+		return err
+	}
+
+	sealed, err := encrypt(buf.Bytes(), passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	if _, err := w.Write(sealed); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
 }
 
-// VerifyBackup verifies that a backup file exists and is readable
-func VerifyBackup(backupPath string) error {
+// maxSequence bounds the sequence suffixes tried for backups taken within the
+// same second
+const maxSequence = 1000
+
+// publishArchive gives a finished temp file its final backup name with the
+// given extension. The name is claimed with a hard link, which fails instead
+// of replacing an existing backup; a taken name moves on to the next sequence
+// suffix. Filesystems without hard links get the name reserved by an
+// exclusive create first.
+func publishArchive(tempPath, dir, profileName string, taken time.Time, ext string) (string, error) {
+	for seq := 1; seq <= maxSequence; seq++ {
+		backupPath := filepath.Join(dir, archiveName(profileName, taken, seq)+ext)
+
+		err := os.Link(tempPath, backupPath)
+		if err == nil {
+			os.Remove(tempPath)
+			return backupPath, nil
+		}
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+
+		reserved, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create backup file: %w", err)
+		}
+		reserved.Close()
+
+		if err := os.Rename(tempPath, backupPath); err != nil {
+			os.Remove(backupPath)
+			return "", fmt.Errorf("failed to move backup into place: %w", err)
+		}
+		return backupPath, nil
+	}
+
+	return "", fmt.Errorf("failed to create backup file: %d backups of %s already exist for %s", maxSequence, profileName, taken.Format(timestampFormat))
+}
+
+// VerifyBackup checks a backup of the profile directory in depth: every file
+// of the profile must be archived, every entry must decompress without CRC
+// errors, and its content must hash to the same SHA-256 as the source file
+// and as recorded in the manifest. Encrypted backups are decrypted with the
+// passphrase first, which also checks that they were not tampered with.
+func VerifyBackup(backupPath, profilePath, passphrase string) error {
 	// Check if file exists
 	info, err := os.Stat(backupPath)
 	if err != nil {
@@ -105,16 +233,128 @@ func VerifyBackup(backupPath string) error {
 	}
 
 	// Try to open and read zip file
-	zipReader, err := zip.OpenReader(backupPath)
+	zipReader, err := openArchive(backupPath, passphrase)
+	if errors.Is(err, ErrPassphraseRequired) || errors.Is(err, ErrWrongPassphrase) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("backup file is not a valid zip: %w", err)
 	}
 	defer zipReader.Close()
 
+	manifest, err := readManifest(zipReader.Reader)
+	if err != nil && !errors.Is(err, ErrNoManifest) {
+		return err
+	}
+	recorded := make(map[string]string)
+	if manifest != nil {
+		for _, f := range manifest.Files {
+			recorded[f.Name] = f.SHA256
+		}
+	}
+
+	// Hash every entry; reading an entry to the end checks its CRC
+	archived := make(map[string]string)
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() || f.Name == ManifestName {
+			continue
+		}
+
+		name, err := entryName(f.Name)
+		if err != nil {
+			return err
+		}
+
+		content, err := readEntry(f)
+		if err != nil {
+			return fmt.Errorf("backup entry %s is damaged: %w", name, err)
+		}
+
+		sum := sha256.Sum256(content)
+		archived[name] = hex.EncodeToString(sum[:])
+
+		if manifest != nil {
+			expected, ok := recorded[name]
+			if !ok {
+				return fmt.Errorf("backup entry %s is missing from the manifest", name)
+			}
+			if archived[name] != expected {
+				return fmt.Errorf("backup entry %s does not match the manifest: SHA-256 %s, expected %s", name, archived[name], expected)
+			}
+		}
+	}
+
 	// Check if zip has at least one file
-	if len(zipReader.File) == 0 {
+	if len(archived) == 0 {
 		return fmt.Errorf("backup zip file is empty")
 	}
 
+	if manifest != nil && len(manifest.Files) != len(archived) {
+		return fmt.Errorf("backup has %d files, the manifest lists %d", len(archived), len(manifest.Files))
+	}
+
+	// Compare with the profile: nothing missing, nothing changed
+	return compareWithProfile(archived, profilePath)
+}
+
+// compareWithProfile checks that the profile directory holds exactly the
+// archived files, given as names with their SHA-256, and that none of them
+// differs from its archived content
+func compareWithProfile(archived map[string]string, profilePath string) error {
+	checked := 0
+	err := filepath.Walk(profilePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(profilePath, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relPath)
+
+		expected, ok := archived[name]
+		if !ok {
+			return fmt.Errorf("profile file %s is not in the backup", name)
+		}
+
+		actual, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("profile file %s differs from its backup (was it modified while the backup was taken?)", name)
+		}
+
+		checked++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if checked != len(archived) {
+		return fmt.Errorf("backup has %d files, the profile has %d", len(archived), checked)
+	}
+
 	return nil
 }
+
+// hashFile returns the hex SHA-256 of a file
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,132 @@
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the zip entry holding the manifest. It is not a profile
+// file and is never restored.
+const ManifestName = ".eve-profile-sync-manifest.json"
+
+// manifestFormat is the version of the manifest layout
+const manifestFormat = 1
+
+// ErrNoManifest is returned for backups made before manifests were added
+var ErrNoManifest = errors.New("backup has no manifest")
+
+// Details describes the circumstances of a backup and is recorded in its manifest
+type Details struct {
+	ToolVersion string
+	Server      string // Server directory name, e.g. c_ccp_eve_online_tq_tranquility
+	Reason      string // Operation that took the backup, e.g. "sync"
+	UserID      string // Source user ID of a sync
+	CharacterID string // Source character ID of a sync
+}
+
+// Manifest describes a backup archive and every file in it
+type Manifest struct {
+	Format      int            `json:"format"`
+	ToolVersion string         `json:"tool_version"`
+	Created     time.Time      `json:"created"`
+	Profile     string         `json:"profile"`
+	ProfilePath string         `json:"profile_path"`
+	Server      string         `json:"server,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+	UserID      string         `json:"user_id,omitempty"`
+	CharacterID string         `json:"character_id,omitempty"`
+	Files       []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file recorded in a manifest
+type ManifestFile struct {
+	Name     string    `json:"name"` // Path relative to the profile directory, with forward slashes
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Modified time.Time `json:"modified"`
+}
+
+// newManifest starts a manifest for a backup of the profile
+func newManifest(profilePath, profileName string, details Details) *Manifest {
+	if abs, err := filepath.Abs(profilePath); err == nil {
+		profilePath = abs
+	}
+
+	return &Manifest{
+		Format:      manifestFormat,
+		ToolVersion: details.ToolVersion,
+		Created:     time.Now(),
+		Profile:     profileName,
+		ProfilePath: profilePath,
+		Server:      details.Server,
+		Reason:      details.Reason,
+		UserID:      details.UserID,
+		CharacterID: details.CharacterID,
+	}
+}
+
+// TotalSize returns the uncompressed size of all files
+func (m *Manifest) TotalSize() int64 {
+	var total int64
+	for _, f := range m.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// writeManifest stores the manifest as the last entry of the archive
+func writeManifest(zipWriter *zip.Writer, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	header := &zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: m.Created}
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to add manifest: %w", err)
+	}
+
+	if _, err := writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// ReadManifest reads the manifest of a backup archive, decrypting it with the
+// passphrase if it is encrypted. Backups without one return ErrNoManifest.
+func ReadManifest(archivePath, passphrase string) (*Manifest, error) {
+	reader, err := openArchive(archivePath, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+
+	return readManifest(reader.Reader)
+}
+
+func readManifest(reader *zip.Reader) (*Manifest, error) {
+	for _, f := range reader.File {
+		if f.Name != ManifestName {
+			continue
+		}
+
+		data, err := readEntry(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		return &m, nil
+	}
+
+	return nil, ErrNoManifest
+}
@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Migrate moves the backups in from, a directory used by older versions,
+// into the per-server, per-profile layout under root and returns how many
+// were moved. The server of a zip backup is read from its manifest; backups
+// without a readable one, like encrypted or very old ones, go to
+// UnknownServer. Snapshots are moved with the blobs they refer to. A backup
+// whose name is already taken under root stays where it is and is reported
+// in the error.
+func Migrate(from, to string) (int, error) {
+	if samePath(from, to) {
+		return 0, nil
+	}
+
+	archives, err := ListArchives(from)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	var errs []error
+	for _, a := range archives {
+		server := a.Server
+		if server == "" {
+			if manifest, err := ReadManifest(a.Path, ""); err == nil {
+				server = manifest.Server
+			}
+		}
+
+		target := filepath.Join(ProfileDir(to, server, a.Profile), filepath.Base(a.Path))
+		if err := moveFile(a.Path, target); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		moved++
+	}
+
+	n, err := OpenStore(from).migrate(OpenStore(to))
+	moved += n
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Remove the old directory if nothing is left in it
+	if len(errs) == 0 {
+		removeEmptyDirs(from)
+	}
+
+	return moved, errors.Join(errs...)
+}
+
+// migrate moves the snapshots of s into dst. Blobs are copied, since other
+// snapshots may share them, and removed from s once every snapshot is moved.
+// Files of s that no snapshot refers to are left alone.
+func (s *Store) migrate(dst *Store) (int, error) {
+	snapshots, err := s.Snapshots()
+	if err != nil || len(snapshots) == 0 {
+		return 0, err
+	}
+
+	moved := make(map[string]bool)
+	for i, snapshot := range snapshots {
+		manifest, err := ReadSnapshot(snapshot.Path)
+		if err != nil {
+			return i, err
+		}
+
+		for _, f := range manifest.Files {
+			moved[f.SHA256] = true
+			target := dst.blobPath(f.SHA256)
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			if err := copyFile(s.blobPath(f.SHA256), target); err != nil {
+				return i, fmt.Errorf("failed to move snapshot %s: %w", snapshot.Name(), err)
+			}
+		}
+
+		target := filepath.Join(ProfileDir(dst.snapshotDir(), manifest.Server, manifest.Profile), filepath.Base(snapshot.Path))
+		if err := moveFile(snapshot.Path, target); err != nil {
+			return i, err
+		}
+	}
+
+	var errs []error
+	for digest := range moved {
+		if err := os.Remove(s.blobPath(digest)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	removeEmptyDirs(s.Root)
+
+	if len(errs) > 0 {
+		return len(snapshots), fmt.Errorf("failed to remove moved blobs from old snapshot store: %w", errors.Join(errs...))
+	}
+	return len(snapshots), nil
+}
+
+// moveFile moves a backup to target, copying it if the two are on different
+// volumes. An existing target is never replaced.
+func moveFile(src, target string) error {
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("cannot move %s: %s already exists", src, target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if err := os.Rename(src, target); err == nil {
+		return nil
+	}
+
+	if err := copyFile(src, target); err != nil {
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove %s after copying it: %w", src, err)
+	}
+	return nil
+}
+
+// copyFile copies src to a new file target, keeping its modification time
+func copyFile(src, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	os.Chtimes(target, info.ModTime(), info.ModTime())
+	return nil
+}
+
+// removeEmptyDirs removes dir and its subdirectories as long as they are empty
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	os.Remove(dir)
+}
+
+// samePath reports whether two paths name the same directory
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	if absA == absB {
+		return true
+	}
+
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
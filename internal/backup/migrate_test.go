@@ -0,0 +1,72 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateMovesOnlyBackups(t *testing.T) {
+	store, profilePath := newTestStore(t, "user settings")
+	from := filepath.Dir(store.Root)
+	to := t.TempDir()
+
+	// A zip backup in the flat layout of older versions
+	zipPath, err := CreateBackup(from, profilePath, "Main", Details{Server: "tq"}, "")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	legacyPath := filepath.Join(from, filepath.Base(zipPath))
+	if err := os.Rename(zipPath, legacyPath); err != nil {
+		t.Fatal(err)
+	}
+	removeEmptyDirs(filepath.Join(from, "tq"))
+
+	snapshotPath, err := store.Snapshot(profilePath, "Main", Details{Server: "tq"})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	// Files that are not backups, and a blob no snapshot refers to
+	unknown := []string{
+		filepath.Join(from, "notes.txt"),
+		filepath.Join(store.Root, "notes.txt"),
+		store.blobPath(digestOf("unused")),
+	}
+	for _, path := range unknown {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := Migrate(from, to)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("moved %d backups, want 2", moved)
+	}
+
+	if _, err := os.Stat(filepath.Join(ProfileDir(to, "tq", "Main"), filepath.Base(zipPath))); err != nil {
+		t.Errorf("zip backup not moved into the server folder: %v", err)
+	}
+	dst := OpenStore(to)
+	movedSnapshot := filepath.Join(ProfileDir(dst.snapshotDir(), "tq", "Main"), filepath.Base(snapshotPath))
+	if err := dst.Verify(movedSnapshot, profilePath); err != nil {
+		t.Errorf("moved snapshot cannot be restored: %v", err)
+	}
+
+	for _, path := range []string{legacyPath, snapshotPath, store.blobPath(digestOf("user settings"))} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was moved but not removed: %v", path, err)
+		}
+	}
+	for _, path := range unknown {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+}
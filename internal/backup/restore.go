@@ -0,0 +1,222 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"eve-profile-sync/internal/sync"
+)
+
+// Entry is a file stored in a backup archive
+type Entry struct {
+	Name     string // Path relative to the profile directory, with forward slashes
+	Size     int64
+	Modified time.Time
+}
+
+// IsCore reports whether the entry is a core_user_*.dat or core_char_*.dat file
+func (e Entry) IsCore() bool {
+	base := path.Base(e.Name)
+	return (strings.HasPrefix(base, "core_user_") || strings.HasPrefix(base, "core_char_")) && strings.HasSuffix(base, ".dat")
+}
+
+// RestoredFile is a file written back into a profile by Restore
+type RestoredFile struct {
+	Name   string
+	Path   string
+	SHA256 string // Hash of the archived content, verified on disk after restoring
+}
+
+// entryName normalizes the name of a zip entry. Backups made on Windows store
+// backslashes; names escaping the profile directory are rejected.
+func entryName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("unsafe entry name in backup: %s", name)
+	}
+	return clean, nil
+}
+
+// ListEntries returns the files stored in a backup archive, decrypting it with
+// the passphrase if it is encrypted
+func ListEntries(archivePath, passphrase string) ([]Entry, error) {
+	reader, err := openArchive(archivePath, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+
+	var entries []Entry
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || f.Name == ManifestName {
+			continue
+		}
+		name, err := entryName(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Name: name, Size: int64(f.UncompressedSize64), Modified: f.Modified})
+	}
+
+	return entries, nil
+}
+
+// Restoration holds backup entries read into memory, ready to be written
+// back into a profile. Reading everything first means a damaged archive is
+// detected before anything is touched, and the archive may be replaced or
+// pruned afterwards without affecting the restore.
+type Restoration struct {
+	Archive string
+	files   []archivedFile
+}
+
+// archivedFile is the content of one backup entry
+type archivedFile struct {
+	name     string
+	content  []byte
+	modified time.Time
+	sha256   string
+}
+
+// PrepareRestore reads entries of a backup archive: the named entries, or
+// every entry when names is empty. Each entry's CRC is checked while reading.
+// Encrypted archives are decrypted with the passphrase, which also
+// authenticates their content.
+func PrepareRestore(archivePath string, names []string, passphrase string) (*Restoration, error) {
+	reader, err := openArchive(archivePath, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer reader.Close()
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	r := &Restoration{Archive: archivePath}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || f.Name == ManifestName {
+			continue
+		}
+
+		name, err := entryName(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 && !wanted[name] {
+			continue
+		}
+		delete(wanted, name)
+
+		// Reading the whole entry makes archive/zip check its CRC
+		content, err := readEntry(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from backup: %w", name, err)
+		}
+
+		sum := sha256.Sum256(content)
+		r.files = append(r.files, archivedFile{
+			name:     name,
+			content:  content,
+			modified: f.Modified,
+			sha256:   hex.EncodeToString(sum[:]),
+		})
+	}
+
+	if len(wanted) > 0 {
+		missing := make([]string, 0, len(wanted))
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("not found in backup %s: %s", filepath.Base(archivePath), strings.Join(missing, ", "))
+	}
+
+	if len(r.files) == 0 {
+		return nil, fmt.Errorf("backup %s contains no files", filepath.Base(archivePath))
+	}
+
+	return r, nil
+}
+
+// Names returns the names of the entries to restore
+func (r *Restoration) Names() []string {
+	names := make([]string, len(r.files))
+	for i, f := range r.files {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Apply writes the entries into the profile directory in a single
+// transaction, then hashes each restored file and compares it with the
+// archived content
+func (r *Restoration) Apply(profilePath string) ([]RestoredFile, error) {
+	tx := sync.NewTransaction()
+	restored := make([]RestoredFile, len(r.files))
+	for i, f := range r.files {
+		target := filepath.Join(profilePath, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", f.name, err)
+		}
+
+		tx.Add(target, f.content)
+		restored[i] = RestoredFile{Name: f.name, Path: target, SHA256: f.sha256}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to restore files: %w", err)
+	}
+
+	for i, f := range r.files {
+		if err := verifyFileHash(restored[i].Path, f.sha256); err != nil {
+			return restored, fmt.Errorf("restored file %s does not match the backup: %w", f.name, err)
+		}
+
+		// Keep the modification time the file had when it was backed up
+		if !f.modified.IsZero() {
+			os.Chtimes(restored[i].Path, f.modified, f.modified)
+		}
+	}
+
+	return restored, nil
+}
+
+// readEntry reads the decompressed content of a zip entry
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// verifyFileHash compares the SHA-256 of a file with an expected hex digest
+func verifyFileHash(filePath, expected string) error {
+	actual, err := hashFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return fmt.Errorf("SHA-256 %s, expected %s", actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Policy decides which backups are kept. Zero fields disable their rule; a
+// zero Policy keeps everything.
+type Policy struct {
+	KeepLast int   // Newest backups kept per profile
+	KeepDays int   // Days for which the newest backup of each day is kept
+	MaxSize  int64 // Upper bound in bytes for all kept backups together
+}
+
+// IsEmpty reports whether the policy never removes anything
+func (p Policy) IsEmpty() bool {
+	return p.KeepLast <= 0 && p.KeepDays <= 0 && p.MaxSize <= 0
+}
+
+// String describes the policy for summaries
+func (p Policy) String() string {
+	if p.IsEmpty() {
+		return "keep all backups"
+	}
+
+	var rules []string
+	if p.KeepLast > 0 {
+		rules = append(rules, fmt.Sprintf("keep last %d per profile", p.KeepLast))
+	}
+	if p.KeepDays > 0 {
+		rules = append(rules, fmt.Sprintf("keep daily for %d days", p.KeepDays))
+	}
+	if p.MaxSize > 0 {
+		rules = append(rules, "at most "+FormatSize(p.MaxSize)+" in total")
+	}
+	return strings.Join(rules, ", ")
+}
+
+// reasonNewest marks the newest backup of a profile, which is never removed
+const reasonNewest = "newest backup"
+
+// PruneEntry is the retention decision for one backup
+type PruneEntry struct {
+	Archive
+	Keep   bool
+	Reason string
+}
+
+// PlanPrune applies the policy to backups listed newest first, as returned by
+// ListArchives. The newest backup of every profile is always kept, so a
+// profile never loses its last restore point.
+func PlanPrune(archives []Archive, p Policy, now time.Time) []PruneEntry {
+	entries := make([]PruneEntry, len(archives))
+	if p.IsEmpty() {
+		for i, a := range archives {
+			entries[i] = PruneEntry{Archive: a, Keep: true, Reason: "no retention policy"}
+		}
+		return entries
+	}
+
+	countRules := p.KeepLast > 0 || p.KeepDays > 0
+	cutoff := now.AddDate(0, 0, -p.KeepDays)
+	seen := make(map[string]int)             // Backups of each profile visited so far
+	days := make(map[string]map[string]bool) // Days of each profile already covered
+
+	for i, a := range archives {
+		entry := PruneEntry{Archive: a}
+		day := a.Time.Format("2006-01-02")
+		key := a.profileKey()
+		if days[key] == nil {
+			days[key] = make(map[string]bool)
+		}
+
+		switch {
+		case seen[key] == 0:
+			entry.Keep, entry.Reason = true, reasonNewest
+		case !countRules:
+			entry.Keep, entry.Reason = true, "within size limit"
+		case seen[key] < p.KeepLast:
+			entry.Keep, entry.Reason = true, fmt.Sprintf("last %d", p.KeepLast)
+		case p.KeepDays > 0 && a.Time.After(cutoff) && !days[key][day]:
+			entry.Keep, entry.Reason = true, "daily backup"
+		case p.KeepDays > 0 && a.Time.After(cutoff):
+			entry.Reason = "newer backup on the same day"
+		case p.KeepDays > 0:
+			entry.Reason = fmt.Sprintf("older than %d days", p.KeepDays)
+		default:
+			entry.Reason = fmt.Sprintf("beyond last %d", p.KeepLast)
+		}
+
+		seen[key]++
+		days[key][day] = true
+		entries[i] = entry
+	}
+
+	if p.MaxSize <= 0 {
+		return entries
+	}
+
+	// Drop the oldest kept backups until the total fits, sparing the newest
+	// backup of each profile
+	var total int64
+	for _, e := range entries {
+		if e.Keep {
+			total += e.Size
+		}
+	}
+	for i := len(entries) - 1; i >= 0 && total > p.MaxSize; i-- {
+		e := &entries[i]
+		if !e.Keep || e.Reason == reasonNewest {
+			continue
+		}
+		e.Keep = false
+		e.Reason = "over size limit of " + FormatSize(p.MaxSize)
+		total -= e.Size
+	}
+
+	return entries
+}
+
+// profileKey identifies the profile of a backup for retention: profiles of
+// the same name on different servers are kept separately
+func (a Archive) profileKey() string {
+	return a.Server + "/" + a.Profile
+}
+
+// Prune removes the backups in dir that the policy does not keep and returns
+// the removed ones
+func Prune(dir string, p Policy) ([]Archive, error) {
+	archives, err := ListArchives(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Archive
+	for _, e := range PlanPrune(archives, p, time.Now()) {
+		if e.Keep {
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", e.Path, err)
+		}
+		removed = append(removed, e.Archive)
+	}
+
+	return removed, nil
+}
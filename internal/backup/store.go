@@ -0,0 +1,496 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// storeDir is the snapshot store inside the backup directory
+const storeDir = "store"
+
+// blobGracePeriod protects recently written or reused blobs from garbage
+// collection. A snapshot stores its blobs before its manifest refers to them,
+// so a prune running meanwhile must not take them for unused.
+const blobGracePeriod = time.Hour
+
+// Store is a content-addressed snapshot store. Every distinct file content is
+// kept once, gzip-compressed, as a blob named by its SHA-256; a snapshot is a
+// manifest listing the files of a profile with their hashes. Files unchanged
+// since an earlier snapshot, or identical to each other as after a sync, cost
+// no extra space.
+type Store struct {
+	Root string
+}
+
+// OpenStore returns the snapshot store of a backup directory. Nothing is
+// created until the first snapshot is taken.
+func OpenStore(backupDir string) *Store {
+	return &Store{Root: filepath.Join(backupDir, storeDir)}
+}
+
+func (s *Store) blobPath(sum string) string {
+	return filepath.Join(s.Root, "blobs", sum[:2], sum)
+}
+
+func (s *Store) snapshotDir() string {
+	return filepath.Join(s.Root, "snapshots")
+}
+
+// Snapshot records the profile directory as a new snapshot and returns the
+// path of its manifest. Manifests are kept in per-server, per-profile
+// folders like zip backups; blobs are shared by all of them.
+func (s *Store) Snapshot(profilePath, profileName string, details Details) (string, error) {
+	dir := ProfileDir(s.snapshotDir(), details.Server, profileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	manifest := newManifest(profilePath, profileName, details)
+
+	err := filepath.Walk(profilePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(profilePath, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		digest := hex.EncodeToString(sum[:])
+		if err := s.putBlob(digest, content); err != nil {
+			return fmt.Errorf("failed to store %s: %w", relPath, err)
+		}
+
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Name:     filepath.ToSlash(relPath),
+			Size:     int64(len(content)),
+			SHA256:   digest,
+			Modified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot manifest: %w", err)
+	}
+
+	tempPath, err := writeTemp(dir, ".settings_"+profileName+"_*.json.tmp", append(data, '\n'))
+	if err != nil {
+		return "", fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+
+	snapshotPath, err := publishArchive(tempPath, dir, profileName, manifest.Created, ".json")
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+
+	return snapshotPath, nil
+}
+
+// putBlob stores content under its hash unless the store already has it. A
+// blob that is reused is touched so that garbage collection leaves it alone
+// until the manifest referring to it is written.
+func (s *Store) putBlob(digest string, content []byte) error {
+	blobPath := s.blobPath(digest)
+	now := time.Now()
+	if err := os.Chtimes(blobPath, now, now); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	tempPath, err := writeTemp(filepath.Dir(blobPath), "."+digest+".*.tmp", buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// A concurrent writer may have stored the same blob; its content is identical
+	if err := os.Rename(tempPath, blobPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// readBlob returns the content stored under a hash, checking that it still
+// hashes to it
+func (s *Store) readBlob(digest string) ([]byte, error) {
+	if len(digest) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob hash %q", digest)
+	}
+
+	file, err := os.Open(s.blobPath(digest))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("blob %s is damaged: %w", digest, err)
+	}
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("blob %s is damaged: %w", digest, err)
+	}
+
+	sum := sha256.Sum256(content)
+	if actual := hex.EncodeToString(sum[:]); actual != digest {
+		return nil, fmt.Errorf("blob %s is damaged: content hashes to %s", digest, actual)
+	}
+
+	return content, nil
+}
+
+// writeTemp writes data to a new temp file in dir and flushes it to disk
+func writeTemp(dir, pattern string, data []byte) (string, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// Snapshots returns the snapshots in the store, newest first. Their size is
+// the total size of their files, most of which are shared with other
+// snapshots.
+func (s *Store) Snapshots() ([]Archive, error) {
+	var snapshots []Archive
+	err := filepath.WalkDir(s.snapshotDir(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.snapshotDir() {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			return nil
+		}
+
+		snapshot, ok := parseArchiveName(strings.TrimSuffix(entry.Name(), ".json"))
+		if !ok {
+			return nil
+		}
+		snapshot.Path = path
+		snapshot.Snapshot = true
+
+		// Snapshots taken before the per-profile layout lie directly in
+		// the snapshots folder
+		if rel, err := filepath.Rel(s.snapshotDir(), path); err == nil {
+			if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) == 3 {
+				snapshot.Server = parts[0]
+			}
+		}
+
+		manifest, err := ReadSnapshot(snapshot.Path)
+		if err != nil {
+			return err
+		}
+		snapshot.Size = manifest.TotalSize()
+
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot store: %w", err)
+	}
+
+	SortArchives(snapshots)
+	return snapshots, nil
+}
+
+// ReadSnapshot reads the manifest of a snapshot
+func ReadSnapshot(snapshotPath string) (*Manifest, error) {
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest %s: %w", filepath.Base(snapshotPath), err)
+	}
+	return &m, nil
+}
+
+// SnapshotEntries returns the files recorded in a snapshot
+func SnapshotEntries(snapshotPath string) ([]Entry, error) {
+	manifest, err := ReadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(manifest.Files))
+	for i, f := range manifest.Files {
+		entries[i] = Entry{Name: f.Name, Size: f.Size, Modified: f.Modified}
+	}
+	return entries, nil
+}
+
+// Verify checks a snapshot in depth: every blob must be present and hash to
+// its name, and when profilePath is set, the profile must hold exactly the
+// recorded files with the recorded content
+func (s *Store) Verify(snapshotPath, profilePath string) error {
+	manifest, err := ReadSnapshot(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	if len(manifest.Files) == 0 {
+		return fmt.Errorf("snapshot is empty: %s", snapshotPath)
+	}
+
+	archived := make(map[string]string, len(manifest.Files))
+	for _, f := range manifest.Files {
+		if _, err := s.readBlob(f.SHA256); err != nil {
+			return fmt.Errorf("snapshot file %s cannot be read: %w", f.Name, err)
+		}
+		archived[f.Name] = f.SHA256
+	}
+
+	if profilePath == "" {
+		return nil
+	}
+	return compareWithProfile(archived, profilePath)
+}
+
+// PrepareRestore reads files of a snapshot: the named ones, or every file
+// when names is empty
+func (s *Store) PrepareRestore(snapshotPath string, names []string) (*Restoration, error) {
+	manifest, err := ReadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	r := &Restoration{Archive: snapshotPath}
+	for _, f := range manifest.Files {
+		if len(names) > 0 && !wanted[f.Name] {
+			continue
+		}
+		delete(wanted, f.Name)
+
+		if _, err := entryName(f.Name); err != nil {
+			return nil, err
+		}
+
+		content, err := s.readBlob(f.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from snapshot: %w", f.Name, err)
+		}
+
+		r.files = append(r.files, archivedFile{name: f.Name, content: content, modified: f.Modified, sha256: f.SHA256})
+	}
+
+	if len(wanted) > 0 {
+		missing := make([]string, 0, len(wanted))
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("not found in snapshot %s: %s", filepath.Base(snapshotPath), strings.Join(missing, ", "))
+	}
+
+	if len(r.files) == 0 {
+		return nil, fmt.Errorf("snapshot %s contains no files", filepath.Base(snapshotPath))
+	}
+
+	return r, nil
+}
+
+// Export writes a snapshot as a zip backup with manifest into dir, in the
+// same format CreateBackup produces, and returns the path of the archive.
+// An empty dir is the profile's folder in the backup directory of the store.
+// With a passphrase the archive is encrypted.
+func (s *Store) Export(snapshotPath, dir, passphrase string) (string, error) {
+	restoration, err := s.PrepareRestore(snapshotPath, nil)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := ReadSnapshot(snapshotPath)
+	if err != nil {
+		return "", err
+	}
+
+	if dir == "" {
+		dir = ProfileDir(filepath.Dir(s.Root), manifest.Server, manifest.Profile)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, f := range restoration.files {
+		header := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: f.modified}
+		header.SetMode(0644)
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return "", fmt.Errorf("failed to export %s: %w", f.name, err)
+		}
+		if _, err := writer.Write(f.content); err != nil {
+			return "", fmt.Errorf("failed to export %s: %w", f.name, err)
+		}
+	}
+	if err := writeManifest(zipWriter, manifest); err != nil {
+		return "", err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize backup: %w", err)
+	}
+
+	data, ext := buf.Bytes(), ".zip"
+	if passphrase != "" {
+		if data, err = encrypt(data, passphrase); err != nil {
+			return "", fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		ext = EncryptedExt
+	}
+
+	tempPath, err := writeTemp(dir, ".settings_"+manifest.Profile+"_*"+ext+".tmp", data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	archivePath, err := publishArchive(tempPath, dir, manifest.Profile, manifest.Created, ext)
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+
+	return archivePath, nil
+}
+
+// Remove deletes snapshots, then every blob no remaining snapshot refers to
+func (s *Store) Remove(snapshotPaths ...string) error {
+	for _, snapshotPath := range snapshotPaths {
+		if err := os.Remove(snapshotPath); err != nil {
+			return fmt.Errorf("failed to remove snapshot %s: %w", filepath.Base(snapshotPath), err)
+		}
+	}
+
+	return s.collectGarbage()
+}
+
+// collectGarbage removes blobs that no snapshot refers to
+func (s *Store) collectGarbage() error {
+	snapshots, err := s.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		manifest, err := ReadSnapshot(snapshot.Path)
+		if err != nil {
+			return err
+		}
+		for _, f := range manifest.Files {
+			referenced[f.SHA256] = true
+		}
+	}
+
+	blobs, _ := filepath.Glob(filepath.Join(s.Root, "blobs", "*", "*"))
+	for _, blob := range blobs {
+		if referenced[filepath.Base(blob)] {
+			continue
+		}
+
+		// Blobs and leftover temp files written or reused within the grace
+		// period may belong to a snapshot that is still being taken
+		if info, err := os.Stat(blob); err != nil || time.Since(info.ModTime()) < blobGracePeriod {
+			continue
+		}
+
+		if err := os.Remove(blob); err != nil {
+			return fmt.Errorf("failed to remove unused blob: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Prune removes the snapshots the policy does not keep, then the blobs they
+// no longer share with other snapshots, and returns the removed snapshots
+func (s *Store) Prune(p Policy) ([]Archive, error) {
+	snapshots, err := s.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Archive
+	var paths []string
+	for _, e := range PlanPrune(snapshots, p, time.Now()) {
+		if !e.Keep {
+			removed = append(removed, e.Archive)
+			paths = append(paths, e.Path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return removed, s.Remove(paths...)
+}
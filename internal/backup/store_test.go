@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStore returns an empty store and a profile holding one file
+func newTestStore(t *testing.T, content string) (*Store, string) {
+	t.Helper()
+	profilePath := filepath.Join(t.TempDir(), "settings_Main")
+	if err := os.MkdirAll(profilePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(profilePath, "core_user_1.dat"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return OpenStore(t.TempDir()), profilePath
+}
+
+// age sets the modification time of a blob back beyond the grace period
+func age(t *testing.T, path string) {
+	t.Helper()
+	old := time.Now().Add(-2 * blobGracePeriod)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRemoveCollectsUnusedBlobs(t *testing.T) {
+	s, profilePath := newTestStore(t, "user settings")
+	snapshot, err := s.Snapshot(profilePath, "Main", Details{Server: "tq"})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	blob := s.blobPath(digestOf("user settings"))
+	age(t, blob)
+	if err := s.Remove(snapshot); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Errorf("unused blob was kept: %v", err)
+	}
+}
+
+func TestRemoveSparesBlobsOfRunningSnapshot(t *testing.T) {
+	s, profilePath := newTestStore(t, "user settings")
+	old, err := s.Snapshot(profilePath, "Main", Details{Server: "tq"})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	digest := digestOf("user settings")
+	age(t, s.blobPath(digest))
+
+	// A new snapshot reuses the blob but has not written its manifest yet
+	// when a prune removes the only snapshot referring to it
+	if err := s.putBlob(digest, []byte("user settings")); err != nil {
+		t.Fatalf("putBlob: %v", err)
+	}
+	if err := s.Remove(old); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// The manifest written now can rely on the blob
+	if _, err := s.readBlob(digest); err != nil {
+		t.Errorf("blob of the running snapshot was removed: %v", err)
+	}
+}
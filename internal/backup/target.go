@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Target is a storage location backups are replicated to. Backups are
+// addressed by keys: slash-separated paths in the layout of the backup
+// directory, {server}/settings_{Profile}/{file}.
+type Target interface {
+	// Name identifies the target in messages and on the command line
+	Name() string
+	// Put stores data under key, replacing an existing object
+	Put(key string, data []byte) error
+	// Get returns the data stored under key
+	Get(key string) ([]byte, error)
+	// List returns every object of the target
+	List() ([]Object, error)
+	// Delete removes the object stored under key
+	Delete(key string) error
+}
+
+// Object is an entry of a target listing
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// DirTarget stores backups in a directory: the local backup directory, or a
+// mirror on another disk or a mounted network share such as a NAS
+type DirTarget struct {
+	name string
+	Root string
+}
+
+// NewDirTarget returns a target storing backups under root
+func NewDirTarget(name, root string) *DirTarget {
+	return &DirTarget{name: name, Root: root}
+}
+
+// Name returns the target name
+func (t *DirTarget) Name() string {
+	return t.name
+}
+
+func (t *DirTarget) path(key string) (string, error) {
+	clean, err := entryName(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(t.Root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes data to a temp file next to its final path and renames it into
+// place, so a share that goes away mid-copy never holds a truncated backup
+func (t *DirTarget) Put(key string, data []byte) error {
+	target, err := t.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory on %s: %w", t.name, err)
+	}
+
+	tempPath, err := writeTemp(filepath.Dir(target), "."+path.Base(key)+".*.tmp", data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s to %s: %w", key, t.name, err)
+	}
+
+	if err := os.Rename(tempPath, target); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write %s to %s: %w", key, t.name, err)
+	}
+	return nil
+}
+
+// Get reads the backup stored under key
+func (t *DirTarget) Get(key string) ([]byte, error) {
+	target, err := t.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(target)
+}
+
+// List returns every file under the root, except temp files. A missing root
+// has no objects.
+func (t *DirTarget) List() ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(t.Root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == t.Root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(t.Root, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", t.name, err)
+	}
+	return objects, nil
+}
+
+// Delete removes the backup stored under key
+func (t *DirTarget) Delete(key string) error {
+	target, err := t.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(target)
+}
+
+// Replicate copies a zip backup from the backup directory root to every
+// target, under the same key, and reads each copy back to check it. A
+// failing target does not stop the others; all failures are returned.
+func Replicate(root, archivePath string, targets []Target) error {
+	rel, err := filepath.Rel(root, archivePath)
+	if err != nil {
+		return fmt.Errorf("backup %s is not in the backup directory: %w", archivePath, err)
+	}
+	key := filepath.ToSlash(rel)
+
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	var errs []error
+	for _, t := range targets {
+		if err := t.Put(key, data); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		stored, err := t.Get(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to read back %s: %w", t.Name(), key, err))
+			continue
+		}
+		if storedSum := sha256.Sum256(stored); !bytes.Equal(storedSum[:], sum[:]) {
+			errs = append(errs, fmt.Errorf("%s: copy of %s does not match the backup", t.Name(), key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ListTarget returns the zip backups stored on a target, newest first. The
+// Path of each is its key on the target.
+func ListTarget(t Target) ([]Archive, error) {
+	objects, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []Archive
+	for _, obj := range objects {
+		parts := strings.Split(obj.Key, "/")
+		if len(parts) != 3 || !strings.HasPrefix(parts[1], "settings_") {
+			continue
+		}
+
+		name := parts[2]
+		encrypted := IsEncrypted(name)
+		switch {
+		case encrypted:
+			name = strings.TrimSuffix(name, EncryptedExt)
+		case path.Ext(name) == ".zip":
+			name = strings.TrimSuffix(name, ".zip")
+		default:
+			continue
+		}
+
+		archive, ok := parseArchiveName(name)
+		if !ok {
+			continue
+		}
+		archive.Path = obj.Key
+		archive.Server = parts[0]
+		archive.Size = obj.Size
+		archive.Encrypted = encrypted
+		archive.Target = t.Name()
+		archives = append(archives, archive)
+	}
+
+	SortArchives(archives)
+	return archives, nil
+}
+
+// Fetch downloads a backup from a target into dir and returns its local
+// path, named like the original so that its format is recognized
+func Fetch(t Target, key, dir string) (string, error) {
+	data, err := t.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s from %s: %w", key, t.Name(), err)
+	}
+
+	localPath := filepath.Join(dir, path.Base(key))
+	if err := os.WriteFile(localPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save %s: %w", key, err)
+	}
+	return localPath, nil
+}
+
+// PruneTarget removes the backups on a target that the policy does not keep
+// and returns the removed ones
+func PruneTarget(t Target, p Policy) ([]Archive, error) {
+	archives, err := ListTarget(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Archive
+	for _, e := range PlanPrune(archives, p, time.Now()) {
+		if e.Keep {
+			continue
+		}
+		if err := t.Delete(e.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s from %s: %w", e.Path, t.Name(), err)
+		}
+		removed = append(removed, e.Archive)
+	}
+
+	return removed, nil
+}
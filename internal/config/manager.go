@@ -10,10 +10,72 @@ import (
 
 // Config represents the application configuration
 type Config struct {
+	Server      string `mapstructure:"server"`
 	ProfilesDir string `mapstructure:"profiles_dir"`
 	Profile     string `mapstructure:"profile"`
 	UserID      string `mapstructure:"user_id"`
 	CharacterID string `mapstructure:"character_id"`
+
+	// Server and profiles that receive the source settings
+	// (the source server and profile when empty)
+	TargetServer   string   `mapstructure:"target_server"`
+	TargetProfiles []string `mapstructure:"target_profiles"`
+
+	// Files deselected as sync targets, remembered between runs
+	ExcludedUserIDs      []string `mapstructure:"excluded_user_ids"`
+	ExcludedCharacterIDs []string `mapstructure:"excluded_character_ids"`
+
+	// Labels for user and character IDs, shown in menus and summaries
+	UserAliases      map[string]string `mapstructure:"user_aliases"`
+	CharacterAliases map[string]string `mapstructure:"character_aliases"`
+
+	// EVE logs directory used to resolve character names
+	// (discovered automatically when empty)
+	LogsDir string `mapstructure:"logs_dir"`
+
+	// Character name lookup through ESI and its local cache. Off by default:
+	// it sends character IDs to a public API.
+	ESIEnabled   bool   `mapstructure:"esi_enabled"`
+	ESIURL       string `mapstructure:"esi_url"`
+	NameCache    string `mapstructure:"name_cache"`     // Cache file; per-user cache directory when empty
+	NameCacheTTL string `mapstructure:"name_cache_ttl"` // Go duration, e.g. "720h"
+
+	// Backup directory; per-user data directory when empty
+	BackupDir string `mapstructure:"backup_dir"`
+
+	// Backup format: "zip" archives or deduplicated "snapshot"s
+	BackupFormat string `mapstructure:"backup_format"`
+
+	// Backup encryption with a passphrase from EVE_PROFILE_SYNC_PASSPHRASE,
+	// the passphrase file or a prompt (zip backups only)
+	BackupEncrypt        bool   `mapstructure:"backup_encrypt"`
+	BackupPassphraseFile string `mapstructure:"backup_passphrase_file"`
+
+	// Backup retention, enforced after every successful backup
+	// (0 or empty disables a rule; all rules are off by default)
+	BackupKeepLast int    `mapstructure:"backup_keep_last"` // Newest backups kept per profile
+	BackupKeepDays int    `mapstructure:"backup_keep_days"` // Days for which one backup per day is kept
+	BackupMaxSize  string `mapstructure:"backup_max_size"`  // Total size limit, e.g. "500MB"
+
+	// Additional locations every zip backup is replicated to
+	BackupTargets []BackupTarget `mapstructure:"backup_targets"`
+}
+
+// BackupTarget is a backup replication target: a mirror directory such as a
+// NAS share, or a bucket of an S3-compatible object storage. S3 credentials
+// fall back to AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+type BackupTarget struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	Type string `mapstructure:"type" yaml:"type"` // "dir" or "s3"
+
+	Path string `mapstructure:"path" yaml:"path,omitempty"` // Directory of a dir target
+
+	Endpoint        string `mapstructure:"endpoint" yaml:"endpoint,omitempty"` // e.g. http://localhost:9000
+	Region          string `mapstructure:"region" yaml:"region,omitempty"`
+	Bucket          string `mapstructure:"bucket" yaml:"bucket,omitempty"`
+	Prefix          string `mapstructure:"prefix" yaml:"prefix,omitempty"`
+	AccessKeyID     string `mapstructure:"access_key_id" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `mapstructure:"secret_access_key" yaml:"secret_access_key,omitempty"`
 }
 
 // LoadConfig loads configuration from file or returns default config
@@ -28,10 +90,30 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath(".")
 
 	// Set defaults
+	viper.SetDefault("server", "")
 	viper.SetDefault("profiles_dir", "")
 	viper.SetDefault("profile", "")
 	viper.SetDefault("user_id", "")
 	viper.SetDefault("character_id", "")
+	viper.SetDefault("target_server", "")
+	viper.SetDefault("target_profiles", []string{})
+	viper.SetDefault("excluded_user_ids", []string{})
+	viper.SetDefault("excluded_character_ids", []string{})
+	viper.SetDefault("user_aliases", map[string]string{})
+	viper.SetDefault("character_aliases", map[string]string{})
+	viper.SetDefault("logs_dir", "")
+	viper.SetDefault("esi_enabled", false)
+	viper.SetDefault("esi_url", "https://esi.evetech.net/latest")
+	viper.SetDefault("name_cache", "")
+	viper.SetDefault("name_cache_ttl", "720h")
+	viper.SetDefault("backup_dir", "")
+	viper.SetDefault("backup_format", "zip")
+	viper.SetDefault("backup_encrypt", false)
+	viper.SetDefault("backup_passphrase_file", "")
+	viper.SetDefault("backup_keep_last", 0)
+	viper.SetDefault("backup_keep_days", 0)
+	viper.SetDefault("backup_max_size", "")
+	viper.SetDefault("backup_targets", []BackupTarget{})
 
 	// Read config file (ignore error if file doesn't exist)
 	if err := viper.ReadInConfig(); err != nil {
@@ -51,10 +133,30 @@ func LoadConfig() (*Config, error) {
 
 // SaveConfig saves configuration to file
 func SaveConfig(cfg *Config) error {
+	viper.Set("server", cfg.Server)
 	viper.Set("profiles_dir", cfg.ProfilesDir)
 	viper.Set("profile", cfg.Profile)
 	viper.Set("user_id", cfg.UserID)
 	viper.Set("character_id", cfg.CharacterID)
+	viper.Set("target_server", cfg.TargetServer)
+	viper.Set("target_profiles", cfg.TargetProfiles)
+	viper.Set("excluded_user_ids", cfg.ExcludedUserIDs)
+	viper.Set("excluded_character_ids", cfg.ExcludedCharacterIDs)
+	viper.Set("user_aliases", cfg.UserAliases)
+	viper.Set("character_aliases", cfg.CharacterAliases)
+	viper.Set("logs_dir", cfg.LogsDir)
+	viper.Set("esi_enabled", cfg.ESIEnabled)
+	viper.Set("esi_url", cfg.ESIURL)
+	viper.Set("name_cache", cfg.NameCache)
+	viper.Set("name_cache_ttl", cfg.NameCacheTTL)
+	viper.Set("backup_dir", cfg.BackupDir)
+	viper.Set("backup_format", cfg.BackupFormat)
+	viper.Set("backup_encrypt", cfg.BackupEncrypt)
+	viper.Set("backup_passphrase_file", cfg.BackupPassphraseFile)
+	viper.Set("backup_keep_last", cfg.BackupKeepLast)
+	viper.Set("backup_keep_days", cfg.BackupKeepDays)
+	viper.Set("backup_max_size", cfg.BackupMaxSize)
+	viper.Set("backup_targets", cfg.BackupTargets)
 
 	// Set config file name and type
 	viper.SetConfigName("config")
@@ -13,24 +13,28 @@ type Profile struct {
 	Path string
 }
 
-// FindProfilesDirectory attempts to find the EVE profiles directory
+// FindProfilesDirectory attempts to find the Tranquility profiles directory
 func FindProfilesDirectory() (string, error) {
 	// Default path: C:\Users\{user}\AppData\Local\CCP\EVE\c_ccp_eve_online_tq_tranquility
-	userHome, err := os.UserHomeDir()
+	eveDir, err := FindEVEDirectory()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
+		return "", err
 	}
 
-	defaultPath := filepath.Join(userHome, "AppData", "Local", "CCP", "EVE", "c_ccp_eve_online_tq_tranquility")
+	servers, err := ListServers(eveDir)
+	if err != nil {
+		return "", err
+	}
 
-	// Check if default path exists
-	if _, err := os.Stat(defaultPath); err == nil {
-		return defaultPath, nil
+	for _, s := range servers {
+		if s.Name == "Tranquility" {
+			return s.Path, nil
+		}
 	}
 
-	// Default path not found, return empty string
+	// Tranquility directory not found, return empty string
 	// The caller should prompt user for path
-	return "", fmt.Errorf("default EVE profiles directory not found at: %s", defaultPath)
+	return "", fmt.Errorf("Tranquility profiles directory not found in: %s", eveDir)
 }
 
 // ValidateProfilesDirectory checks if a directory exists and is accessible
@@ -0,0 +1,270 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultESIURL is the base URL of EVE's public API
+	DefaultESIURL = "https://esi.evetech.net/latest"
+
+	// DefaultNameCacheTTL is how long names fetched from ESI are trusted
+	DefaultNameCacheTTL = 30 * 24 * time.Hour
+
+	// esiBatchSize is the most IDs POST /universe/names/ accepts at once
+	esiBatchSize = 1000
+)
+
+// errUnknownIDs is returned by ESI (HTTP 404) when a batch contains an ID it
+// cannot resolve
+var errUnknownIDs = errors.New("ESI does not know some IDs")
+
+// ESINames resolves character names through ESI's POST /universe/names/
+// endpoint. Results are kept in a JSON cache file, so names stay available
+// without network access.
+type ESINames struct {
+	BaseURL   string
+	CachePath string
+	TTL       time.Duration
+	Client    *http.Client
+}
+
+// NewESINames creates an ESI name source with the given base URL, cache file
+// and cache lifetime
+func NewESINames(baseURL, cachePath string, ttl time.Duration) *ESINames {
+	return &ESINames{
+		BaseURL:   strings.TrimSuffix(baseURL, "/"),
+		CachePath: cachePath,
+		TTL:       ttl,
+		Client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// DefaultNameCachePath returns names.json in the per-user cache directory
+func DefaultNameCachePath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "name_cache.json"
+	}
+	return filepath.Join(cacheDir, "eve-profile-sync", "names.json")
+}
+
+// cachedName is a name fetched from ESI. An empty name records an ID ESI
+// does not know, so it is not requested again until the entry expires.
+type cachedName struct {
+	Name     string    `json:"name"`
+	Category string    `json:"category,omitempty"`
+	Fetched  time.Time `json:"fetched"`
+}
+
+type nameCache struct {
+	Names map[string]cachedName `json:"names"`
+}
+
+// esiName is an entry of the POST /universe/names/ response
+type esiName struct {
+	Category string `json:"category"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+}
+
+// LookupNames returns cached names and fetches the missing or expired ones.
+// If ESI cannot be reached, expired cache entries are used instead and the
+// error is returned with them.
+func (e *ESINames) LookupNames(ids []string) (map[string]string, error) {
+	cache := e.loadCache()
+	now := time.Now()
+
+	names := make(map[string]string)
+	var fetch, stale []string
+	for _, id := range ids {
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			continue
+		}
+
+		entry, ok := cache.Names[id]
+		switch {
+		case !ok:
+			fetch = append(fetch, id)
+		case now.Sub(entry.Fetched) > e.TTL:
+			fetch = append(fetch, id)
+			stale = append(stale, id)
+		case entry.Name != "":
+			names[id] = entry.Name
+		}
+	}
+
+	if len(fetch) == 0 {
+		return names, nil
+	}
+
+	fetched, fetchErr := e.fetch(fetch)
+	for _, id := range fetch {
+		if entry, ok := fetched[id]; ok {
+			cache.Names[id] = entry
+			if entry.Name != "" {
+				names[id] = entry.Name
+			}
+		}
+	}
+
+	if fetchErr != nil {
+		// Offline: fall back to what the cache still has
+		for _, id := range stale {
+			if _, ok := fetched[id]; !ok && cache.Names[id].Name != "" {
+				names[id] = cache.Names[id].Name
+			}
+		}
+	}
+
+	if len(fetched) > 0 {
+		if err := e.saveCache(cache); err != nil && fetchErr == nil {
+			fetchErr = err
+		}
+	}
+
+	return names, fetchErr
+}
+
+// fetch resolves IDs in batches. IDs ESI does not know are returned with an
+// empty name.
+func (e *ESINames) fetch(ids []string) (map[string]cachedName, error) {
+	fetched := make(map[string]cachedName)
+	for start := 0; start < len(ids); start += esiBatchSize {
+		end := min(start+esiBatchSize, len(ids))
+		if err := e.fetchBatch(ids[start:end], fetched); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, nil
+}
+
+// fetchBatch resolves one batch. ESI rejects a whole batch if any ID is
+// unknown, so such batches are split until the unknown IDs are isolated.
+func (e *ESINames) fetchBatch(ids []string, fetched map[string]cachedName) error {
+	now := time.Now()
+
+	results, err := e.postNames(ids)
+	if errors.Is(err, errUnknownIDs) {
+		if len(ids) == 1 {
+			fetched[ids[0]] = cachedName{Fetched: now}
+			return nil
+		}
+		half := len(ids) / 2
+		if err := e.fetchBatch(ids[:half], fetched); err != nil {
+			return err
+		}
+		return e.fetchBatch(ids[half:], fetched)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		fetched[id] = cachedName{Fetched: now}
+	}
+	for _, r := range results {
+		fetched[strconv.FormatInt(r.ID, 10)] = cachedName{Name: r.Name, Category: r.Category, Fetched: now}
+	}
+
+	return nil
+}
+
+// postNames calls POST /universe/names/ for a list of IDs
+func (e *ESINames) postNames(ids []string) ([]esiName, error) {
+	body := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", id)
+		}
+		body = append(body, n)
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.BaseURL+"/universe/names/", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ESI request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "eve-profile-sync")
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ESI request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errUnknownIDs
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("ESI request failed: %s", resp.Status)
+	}
+
+	var results []esiName
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to parse ESI response: %w", err)
+	}
+
+	return results, nil
+}
+
+// loadCache reads the cache file; a missing or unreadable cache is empty
+func (e *ESINames) loadCache() *nameCache {
+	cache := &nameCache{}
+	if data, err := os.ReadFile(e.CachePath); err == nil {
+		json.Unmarshal(data, cache)
+	}
+	if cache.Names == nil {
+		cache.Names = make(map[string]cachedName)
+	}
+	return cache
+}
+
+// saveCache writes the cache file through a temp file, so an interrupted
+// write never leaves a truncated cache
+func (e *ESINames) saveCache(cache *nameCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(e.CachePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create name cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".names-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write name cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write name cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write name cache: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), e.CachePath); err != nil {
+		return fmt.Errorf("failed to write name cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// esiStub is a stand-in for POST /universe/names/. Like ESI, it rejects a
+// whole batch with 404 if any ID is unknown.
+type esiStub struct {
+	mu      sync.Mutex
+	known   map[int64]string
+	batches [][]int64
+	fail    bool // Answer every request with 503
+}
+
+func (s *esiStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/universe/names/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var ids []int64
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, ids)
+	fail := s.fail
+	s.mu.Unlock()
+
+	if fail {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var results []esiName
+	for _, id := range ids {
+		name, ok := s.known[id]
+		if !ok {
+			http.Error(w, `{"error":"Ensure all IDs are valid before resolving."}`, http.StatusNotFound)
+			return
+		}
+		results = append(results, esiName{Category: "character", ID: id, Name: name})
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// requests returns the number of requests served so far
+func (s *esiStub) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+func newESIStub(t *testing.T, known map[int64]string) (*esiStub, *ESINames) {
+	t.Helper()
+	stub := &esiStub{known: known}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	names := NewESINames(server.URL+"/", filepath.Join(t.TempDir(), "names.json"), time.Hour)
+	return stub, names
+}
+
+func TestESINamesBatches(t *testing.T) {
+	known := make(map[int64]string)
+	var ids []string
+	for i := int64(1); i <= 2500; i++ {
+		id := 90000000 + i
+		known[id] = "Pilot " + strconv.FormatInt(i, 10)
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	stub, esi := newESIStub(t, known)
+
+	names, err := esi.LookupNames(ids)
+	if err != nil {
+		t.Fatalf("LookupNames: %v", err)
+	}
+	if len(names) != len(ids) {
+		t.Fatalf("got %d names, want %d", len(names), len(ids))
+	}
+	if names["90000042"] != "Pilot 42" {
+		t.Errorf("name of 90000042 = %q, want %q", names["90000042"], "Pilot 42")
+	}
+
+	var sizes []int
+	for _, batch := range stub.batches {
+		sizes = append(sizes, len(batch))
+	}
+	if len(sizes) != 3 || sizes[0] != esiBatchSize || sizes[1] != esiBatchSize || sizes[2] != 500 {
+		t.Errorf("batch sizes = %v, want [1000 1000 500]", sizes)
+	}
+
+	// Cached names are not requested again
+	if _, err := esi.LookupNames(ids); err != nil {
+		t.Fatalf("LookupNames: %v", err)
+	}
+	if stub.requests() != 3 {
+		t.Errorf("cached names were requested again: %d requests", stub.requests())
+	}
+}
+
+func TestESINamesIsolatesUnknownIDs(t *testing.T) {
+	known := map[int64]string{}
+	ids := []string{"101", "102", "103", "104", "105", "106", "107", "108"}
+	for _, id := range ids {
+		n, _ := strconv.ParseInt(id, 10, 64)
+		known[n] = "Pilot " + id
+	}
+	delete(known, 103)
+	delete(known, 108)
+	stub, esi := newESIStub(t, known)
+
+	names, err := esi.LookupNames(ids)
+	if err != nil {
+		t.Fatalf("LookupNames: %v", err)
+	}
+
+	for _, id := range ids {
+		_, got := names[id]
+		want := id != "103" && id != "108"
+		if got != want {
+			t.Errorf("name of %s returned: %v, want %v", id, got, want)
+		}
+	}
+
+	// Bisection stops at single unknown IDs, which are cached as unknown
+	requests := stub.requests()
+	if requests > 2*len(ids) {
+		t.Errorf("%d requests for %d IDs", requests, len(ids))
+	}
+	if _, err := esi.LookupNames(ids); err != nil {
+		t.Fatalf("LookupNames: %v", err)
+	}
+	if stub.requests() != requests {
+		t.Errorf("unknown IDs were requested again")
+	}
+}
+
+func TestESINamesFallsBackToStaleCache(t *testing.T) {
+	stub, esi := newESIStub(t, map[int64]string{201: "Renamed Pilot"})
+
+	// An expired entry from an earlier run
+	cache := nameCache{Names: map[string]cachedName{
+		"201": {Name: "Old Pilot", Category: "character", Fetched: time.Now().Add(-48 * time.Hour)},
+	}}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(esi.CachePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// ESI is down: the expired name is used and the error reported
+	stub.fail = true
+	names, err := esi.LookupNames([]string{"201"})
+	if err == nil {
+		t.Error("LookupNames did not report the failed request")
+	}
+	if names["201"] != "Old Pilot" {
+		t.Errorf("name of 201 = %q, want the cached %q", names["201"], "Old Pilot")
+	}
+
+	// ESI is back: the expired entry is refreshed
+	stub.fail = false
+	names, err = esi.LookupNames([]string{"201"})
+	if err != nil {
+		t.Fatalf("LookupNames: %v", err)
+	}
+	if names["201"] != "Renamed Pilot" {
+		t.Errorf("name of 201 = %q, want %q", names["201"], "Renamed Pilot")
+	}
+}
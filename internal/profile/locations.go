@@ -0,0 +1,206 @@
+package profile
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// eveSteamAppID is the Steam application ID of EVE Online, used to locate the
+// Proton prefix under steamapps/compatdata
+const eveSteamAppID = "8500"
+
+// Installation is a CCP\EVE settings directory found on this machine
+type Installation struct {
+	Location string // Where the directory was found, e.g. "Windows", "Steam Proton"
+	Path     string
+}
+
+// FindInstallations probes every known location of the CCP\EVE settings
+// directory: native Windows, Steam Proton compatdata prefixes, Wine and Lutris
+// prefixes, and the macOS client. Only existing directories are returned.
+func FindInstallations() []Installation {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var found []Installation
+	seen := make(map[string]bool)
+	for _, candidate := range candidateInstallations(userHome) {
+		info, err := os.Stat(candidate.Path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		// The same directory is often reachable through several symlinks,
+		// e.g. ~/.steam/steam and ~/.local/share/Steam
+		key := candidate.Path
+		if resolved, err := filepath.EvalSymlinks(candidate.Path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		found = append(found, candidate)
+	}
+
+	return found
+}
+
+// candidateInstallations lists every path where a CCP\EVE directory may exist
+func candidateInstallations(userHome string) []Installation {
+	eveSubPath := filepath.Join("AppData", "Local", "CCP", "EVE")
+
+	var candidates []Installation
+
+	// Native Windows client
+	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+		candidates = append(candidates, Installation{Location: "Windows", Path: filepath.Join(localAppData, "CCP", "EVE")})
+	}
+	candidates = append(candidates, Installation{Location: "Windows", Path: filepath.Join(userHome, eveSubPath)})
+
+	// Steam Proton: steamapps/compatdata/8500/pfx in every Steam library
+	for _, library := range steamLibraries(userHome) {
+		prefix := filepath.Join(library, "steamapps", "compatdata", eveSteamAppID, "pfx")
+		candidates = append(candidates, Installation{
+			Location: "Steam Proton",
+			Path:     filepath.Join(prefix, "drive_c", "users", "steamuser", eveSubPath),
+		})
+	}
+
+	// Wine prefixes: $WINEPREFIX, the default ~/.wine and Lutris game prefixes
+	var winePrefixes []Installation
+	if prefix := os.Getenv("WINEPREFIX"); prefix != "" {
+		winePrefixes = append(winePrefixes, Installation{Location: "Wine", Path: prefix})
+	}
+	winePrefixes = append(winePrefixes, Installation{Location: "Wine", Path: filepath.Join(userHome, ".wine")})
+	if matches, err := filepath.Glob(filepath.Join(userHome, "Games", "*")); err == nil {
+		for _, match := range matches {
+			winePrefixes = append(winePrefixes, Installation{Location: "Lutris", Path: match})
+		}
+	}
+	for _, prefix := range winePrefixes {
+		users, err := filepath.Glob(filepath.Join(prefix.Path, "drive_c", "users", "*"))
+		if err != nil {
+			continue
+		}
+		for _, user := range users {
+			candidates = append(candidates, Installation{Location: prefix.Location, Path: filepath.Join(user, eveSubPath)})
+		}
+	}
+
+	// macOS: native client and the older Wine-wrapped client container
+	appSupport := filepath.Join(userHome, "Library", "Application Support")
+	candidates = append(candidates,
+		Installation{Location: "macOS", Path: filepath.Join(appSupport, "CCP", "EVE")},
+		Installation{Location: "macOS", Path: filepath.Join(appSupport, "EVE Online", "p_drive", "User", "Local Settings", "Application Data", "CCP", "EVE")},
+	)
+
+	return candidates
+}
+
+// libraryPathRegex matches "path" entries in Steam's libraryfolders.vdf
+var libraryPathRegex = regexp.MustCompile(`^\s*"path"\s+"(.+)"\s*$`)
+
+// steamLibraries returns the Steam installation roots and every additional
+// library folder they reference
+func steamLibraries(userHome string) []string {
+	roots := []string{
+		filepath.Join(userHome, ".steam", "steam"),
+		filepath.Join(userHome, ".local", "share", "Steam"),
+		filepath.Join(userHome, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		filepath.Join(userHome, "snap", "steam", "common", ".local", "share", "Steam"),
+	}
+
+	libraries := append([]string{}, roots...)
+	for _, root := range roots {
+		file, err := os.Open(filepath.Join(root, "steamapps", "libraryfolders.vdf"))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			matches := libraryPathRegex.FindStringSubmatch(scanner.Text())
+			if len(matches) == 2 {
+				libraries = append(libraries, strings.ReplaceAll(matches[1], `\\`, `\`))
+			}
+		}
+		file.Close()
+	}
+
+	return libraries
+}
+
+// FindLogDirectories returns the EVE client's log directories (Documents\EVE\logs)
+// belonging to every user profile that has an EVE installation, plus the
+// current user's Documents folder. Only existing directories are returned.
+func FindLogDirectories() []string {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidateLogDirectories(userHome) {
+		info, err := os.Stat(candidate)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		key := candidate
+		if resolved, err := filepath.EvalSymlinks(candidate); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		found = append(found, candidate)
+	}
+
+	return found
+}
+
+// candidateLogDirectories lists every path where EVE logs may exist
+func candidateLogDirectories(userHome string) []string {
+	logsSubPath := filepath.Join("EVE", "logs")
+	eveSubPath := filepath.Join("AppData", "Local", "CCP", "EVE")
+
+	// Documents folders of the current user, including OneDrive redirection
+	userDirs := []string{userHome}
+	if profileDir := os.Getenv("USERPROFILE"); profileDir != "" {
+		userDirs = append(userDirs, profileDir)
+	}
+
+	// Users of Proton and Wine prefixes that have an EVE installation
+	for _, installation := range FindInstallations() {
+		if strings.HasSuffix(installation.Path, eveSubPath) {
+			userDirs = append(userDirs, strings.TrimSuffix(installation.Path, string(filepath.Separator)+eveSubPath))
+		}
+	}
+
+	var candidates []string
+	if oneDrive := os.Getenv("OneDrive"); oneDrive != "" {
+		candidates = append(candidates, filepath.Join(oneDrive, "Documents", logsSubPath))
+	}
+	for _, userDir := range userDirs {
+		candidates = append(candidates,
+			filepath.Join(userDir, "Documents", logsSubPath),
+			filepath.Join(userDir, "My Documents", logsSubPath),
+			filepath.Join(userDir, "OneDrive", "Documents", logsSubPath),
+		)
+	}
+
+	// Older Wine-wrapped macOS client
+	candidates = append(candidates, filepath.Join(userHome, "Library", "Application Support", "EVE Online", "p_drive", "User", "My Documents", logsSubPath))
+
+	return candidates
+}
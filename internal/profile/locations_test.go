@@ -0,0 +1,215 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// windowsEVE is the CCP\EVE directory inside a Windows user directory
+var windowsEVE = filepath.Join("AppData", "Local", "CCP", "EVE")
+
+// protonEVE is the CCP\EVE directory of the EVE Proton prefix in a Steam library
+var protonEVE = filepath.Join("steamapps", "compatdata", "8500", "pfx", "drive_c", "users", "steamuser", windowsEVE)
+
+// makeServers creates server directories with one profile each under eveDir
+func makeServers(t *testing.T, eveDir string, dirNames ...string) {
+	t.Helper()
+	for _, dirName := range dirNames {
+		if err := os.MkdirAll(filepath.Join(eveDir, dirName, "settings_Default"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscoverServersLocations(t *testing.T) {
+	const tq = "c_ccp_eve_online_tq_tranquility"
+	const sisi = "c_ccp_eve_online_sisi_singularity"
+
+	type want struct {
+		name, location, path string // path relative to the test root
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, root, home string)
+		want  []want
+	}{
+		{
+			name: "native Windows",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, windowsEVE), tq)
+			},
+			want: []want{{"Tranquility", "Windows", filepath.Join("home", windowsEVE, tq)}},
+		},
+		{
+			name: "LOCALAPPDATA",
+			setup: func(t *testing.T, root, home string) {
+				t.Setenv("LOCALAPPDATA", filepath.Join(root, "local"))
+				makeServers(t, filepath.Join(root, "local", "CCP", "EVE"), sisi)
+			},
+			want: []want{{"Singularity", "Windows", filepath.Join("local", "CCP", "EVE", sisi)}},
+		},
+		{
+			name: "Steam Proton",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, ".steam", "steam", protonEVE), tq)
+			},
+			want: []want{{"Tranquility", "Steam Proton", filepath.Join("home", ".steam", "steam", protonEVE, tq)}},
+		},
+		{
+			name: "Steam Proton in Flatpak Steam",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam", protonEVE), tq)
+			},
+			want: []want{{"Tranquility", "Steam Proton", filepath.Join("home", ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam", protonEVE, tq)}},
+		},
+		{
+			name: "Steam Proton in another library",
+			setup: func(t *testing.T, root, home string) {
+				steamapps := filepath.Join(home, ".local", "share", "Steam", "steamapps")
+				if err := os.MkdirAll(steamapps, 0755); err != nil {
+					t.Fatal(err)
+				}
+				vdf := "\"libraryfolders\"\n{\n\t\"1\"\n\t{\n\t\t\"path\"\t\t\"" + filepath.Join(root, "games") + "\"\n\t}\n}\n"
+				if err := os.WriteFile(filepath.Join(steamapps, "libraryfolders.vdf"), []byte(vdf), 0644); err != nil {
+					t.Fatal(err)
+				}
+				makeServers(t, filepath.Join(root, "games", protonEVE), tq)
+			},
+			want: []want{{"Tranquility", "Steam Proton", filepath.Join("games", protonEVE, tq)}},
+		},
+		{
+			name: "WINEPREFIX",
+			setup: func(t *testing.T, root, home string) {
+				t.Setenv("WINEPREFIX", filepath.Join(root, "prefix"))
+				makeServers(t, filepath.Join(root, "prefix", "drive_c", "users", "alice", windowsEVE), tq)
+			},
+			want: []want{{"Tranquility", "Wine", filepath.Join("prefix", "drive_c", "users", "alice", windowsEVE, tq)}},
+		},
+		{
+			name: "default Wine prefix",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, ".wine", "drive_c", "users", "bob", windowsEVE), tq)
+			},
+			want: []want{{"Tranquility", "Wine", filepath.Join("home", ".wine", "drive_c", "users", "bob", windowsEVE, tq)}},
+		},
+		{
+			name: "Lutris",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, "Games", "eve-online", "drive_c", "users", "carol", windowsEVE), tq)
+			},
+			want: []want{{"Tranquility", "Lutris", filepath.Join("home", "Games", "eve-online", "drive_c", "users", "carol", windowsEVE, tq)}},
+		},
+		{
+			name: "macOS",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, "Library", "Application Support", "CCP", "EVE"), tq)
+			},
+			want: []want{{"Tranquility", "macOS", filepath.Join("home", "Library", "Application Support", "CCP", "EVE", tq)}},
+		},
+		{
+			name: "macOS Wine container",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, "Library", "Application Support", "EVE Online", "p_drive", "User", "Local Settings", "Application Data", "CCP", "EVE"), tq)
+			},
+			want: []want{{"Tranquility", "macOS", filepath.Join("home", "Library", "Application Support", "EVE Online", "p_drive", "User", "Local Settings", "Application Data", "CCP", "EVE", tq)}},
+		},
+		{
+			name: "several servers, directories without profiles skipped",
+			setup: func(t *testing.T, root, home string) {
+				eveDir := filepath.Join(home, windowsEVE)
+				makeServers(t, eveDir, sisi, tq)
+				if err := os.MkdirAll(filepath.Join(eveDir, "SharedCache"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []want{
+				{"Singularity", "Windows", filepath.Join("home", windowsEVE, sisi)},
+				{"Tranquility", "Windows", filepath.Join("home", windowsEVE, tq)},
+			},
+		},
+		{
+			name: "Steam root reachable through a symlink",
+			setup: func(t *testing.T, root, home string) {
+				makeServers(t, filepath.Join(home, ".steam", "steam", protonEVE), tq)
+				if err := os.MkdirAll(filepath.Join(home, ".local", "share"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(home, ".steam", "steam"), filepath.Join(home, ".local", "share", "Steam")); err != nil {
+					t.Skip("symlinks not supported:", err)
+				}
+			},
+			want: []want{{"Tranquility", "Steam Proton", filepath.Join("home", ".steam", "steam", protonEVE, tq)}},
+		},
+		{
+			name: "WINEPREFIX pointing at the default prefix",
+			setup: func(t *testing.T, root, home string) {
+				t.Setenv("WINEPREFIX", filepath.Join(home, ".wine"))
+				makeServers(t, filepath.Join(home, ".wine", "drive_c", "users", "bob", windowsEVE), tq)
+			},
+			want: []want{{"Tranquility", "Wine", filepath.Join("home", ".wine", "drive_c", "users", "bob", windowsEVE, tq)}},
+		},
+		{
+			name: "every location at once",
+			setup: func(t *testing.T, root, home string) {
+				t.Setenv("WINEPREFIX", filepath.Join(root, "prefix"))
+				makeServers(t, filepath.Join(home, windowsEVE), tq)
+				makeServers(t, filepath.Join(home, ".steam", "steam", protonEVE), tq)
+				makeServers(t, filepath.Join(root, "prefix", "drive_c", "users", "alice", windowsEVE), tq)
+				makeServers(t, filepath.Join(home, "Games", "eve-online", "drive_c", "users", "carol", windowsEVE), sisi)
+				makeServers(t, filepath.Join(home, "Library", "Application Support", "CCP", "EVE"), tq)
+			},
+			want: []want{
+				{"Tranquility", "Windows", filepath.Join("home", windowsEVE, tq)},
+				{"Tranquility", "Steam Proton", filepath.Join("home", ".steam", "steam", protonEVE, tq)},
+				{"Tranquility", "Wine", filepath.Join("prefix", "drive_c", "users", "alice", windowsEVE, tq)},
+				{"Singularity", "Lutris", filepath.Join("home", "Games", "eve-online", "drive_c", "users", "carol", windowsEVE, sisi)},
+				{"Tranquility", "macOS", filepath.Join("home", "Library", "Application Support", "CCP", "EVE", tq)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			home := filepath.Join(root, "home")
+			if err := os.MkdirAll(home, 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("HOME", home)
+			t.Setenv("USERPROFILE", home)
+			t.Setenv("LOCALAPPDATA", "")
+			t.Setenv("WINEPREFIX", "")
+			tt.setup(t, root, home)
+
+			servers, err := DiscoverServers()
+			if err != nil {
+				t.Fatalf("DiscoverServers: %v", err)
+			}
+
+			if len(servers) != len(tt.want) {
+				t.Fatalf("found %d servers, want %d: %+v", len(servers), len(tt.want), servers)
+			}
+			for i, w := range tt.want {
+				got := servers[i]
+				if got.Name != w.name || got.Location != w.location || got.Path != filepath.Join(root, w.path) {
+					t.Errorf("server %d = %s in %s at %s, want %s in %s at %s",
+						i, got.Name, got.Location, got.Path, w.name, w.location, filepath.Join(root, w.path))
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoverServersNothingFound(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("LOCALAPPDATA", "")
+	t.Setenv("WINEPREFIX", "")
+
+	if servers, err := DiscoverServers(); err == nil {
+		t.Errorf("DiscoverServers found %+v in an empty home directory", servers)
+	}
+}
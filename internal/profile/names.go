@@ -0,0 +1,205 @@
+package profile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// NameSource resolves character IDs to character names
+type NameSource interface {
+	// LookupNames returns the names it knows for the given IDs. IDs it cannot
+	// resolve are left out of the result.
+	LookupNames(ids []string) (map[string]string, error)
+}
+
+// ResolveNames asks each source in turn for the IDs still unresolved. Names
+// found before a source fails are kept; the first error is returned with them.
+func ResolveNames(sources []NameSource, ids []string) (map[string]string, error) {
+	names := make(map[string]string)
+	var firstErr error
+
+	for _, source := range sources {
+		var missing []string
+		for _, id := range ids {
+			if _, ok := names[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+
+		found, err := source.LookupNames(missing)
+		for id, name := range found {
+			names[id] = name
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return names, firstErr
+}
+
+// ApplyCharacterNames fills in the names of character files that have none
+func ApplyCharacterNames(files []CharacterFile, names map[string]string) {
+	for i := range files {
+		if files[i].Name == "" {
+			files[i].Name = names[files[i].ID]
+		}
+	}
+}
+
+// ApplyUserAliases sets the registered alias of every user file
+func ApplyUserAliases(files []UserFile, aliases map[string]string) {
+	for i := range files {
+		files[i].Alias = aliases[files[i].ID]
+	}
+}
+
+// ApplyCharacterAliases sets the registered alias of every character file
+func ApplyCharacterAliases(files []CharacterFile, aliases map[string]string) {
+	for i := range files {
+		files[i].Alias = aliases[files[i].ID]
+	}
+}
+
+// logFileRegex matches chat and game log names: an optional channel name,
+// the session start date and time, and the listener's character ID. Logs
+// written by old clients have no character ID and are ignored.
+var logFileRegex = regexp.MustCompile(`^(?:.+_)?(\d{8}_\d{6})_(\d+)\.txt$`)
+
+// logHeaderSize is how much of a log is read to find the header
+const logHeaderSize = 4096
+
+// LogNames resolves character names from the EVE client's chat and game logs,
+// whose headers name the listening character
+type LogNames struct {
+	Dirs []string // EVE logs directories containing Chatlogs and Gamelogs
+
+	looked map[string]string // IDs already looked up, with their name or ""
+}
+
+// NewLogNames creates a name source reading the logs in the given
+// directories. Each may be the logs directory itself or one of its
+// Chatlogs/Gamelogs subdirectories.
+func NewLogNames(dirs []string) *LogNames {
+	return &LogNames{Dirs: dirs, looked: make(map[string]string)}
+}
+
+// logFile is a log that belongs to a character
+type logFile struct {
+	path    string
+	started string // Session start, yyyymmdd_hhmmss
+}
+
+// LookupNames returns the names found in the newest log of every ID
+func (l *LogNames) LookupNames(ids []string) (map[string]string, error) {
+	names := make(map[string]string)
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if name, ok := l.looked[id]; ok {
+			if name != "" {
+				names[id] = name
+			}
+			continue
+		}
+		wanted[id] = true
+	}
+
+	if len(wanted) == 0 {
+		return names, nil
+	}
+
+	logs := make(map[string][]logFile)
+	for _, dir := range l.Dirs {
+		for _, sub := range []string{dir, filepath.Join(dir, "Chatlogs"), filepath.Join(dir, "Gamelogs")} {
+			collectLogs(sub, wanted, logs)
+		}
+	}
+
+	for id := range wanted {
+		files := logs[id]
+		// Newest first: characters can be renamed
+		sort.Slice(files, func(i, j int) bool { return files[i].started > files[j].started })
+		for _, f := range files {
+			if name := readListener(f.path); name != "" {
+				names[id] = name
+				break
+			}
+		}
+		l.looked[id] = names[id]
+	}
+
+	return names, nil
+}
+
+// collectLogs adds the logs of wanted IDs in dir to logs
+func collectLogs(dir string, wanted map[string]bool, logs map[string][]logFile) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := logFileRegex.FindStringSubmatch(entry.Name())
+		if matches == nil || !wanted[matches[2]] {
+			continue
+		}
+		logs[matches[2]] = append(logs[matches[2]], logFile{path: filepath.Join(dir, entry.Name()), started: matches[1]})
+	}
+}
+
+// readListener returns the name on the "Listener:" line of a log header
+func readListener(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	header := make([]byte, logHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+
+	for _, line := range strings.Split(decodeLogText(header[:n]), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "Listener" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// decodeLogText decodes a log: chat logs are UTF-16LE, game logs UTF-8
+func decodeLogText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16LE(data[2:])
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case len(data) > 1 && data[0] != 0 && data[1] == 0:
+		// UTF-16LE without byte order mark
+		return decodeUTF16LE(data)
+	}
+	return string(data)
+}
+
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[i*2]) | uint16(data[i*2+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
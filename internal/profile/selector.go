@@ -9,16 +9,53 @@ import (
 
 // UserFile represents a user configuration file
 type UserFile struct {
-	ID   string
-	Name string // May be empty if extraction fails
-	Path string
+	ID    string
+	Name  string // Not stored in the settings files; empty unless set by the caller
+	Alias string // Label registered by the user, may be empty
+	Path  string
 }
 
 // CharacterFile represents a character configuration file
 type CharacterFile struct {
-	ID   string
-	Name string // May be empty if extraction fails
-	Path string
+	ID    string
+	Name  string // Resolved from the chat logs or ESI, may be empty
+	Alias string // Label registered by the user, may be empty
+	Path  string
+}
+
+// Describe returns the user ID followed by the known name and alias
+func (f UserFile) Describe() string {
+	return describeID(f.ID, f.Name, f.Alias)
+}
+
+// Label returns the user file as shown in menus and listings
+func (f UserFile) Label() string {
+	return "User ID: " + f.Describe()
+}
+
+// Describe returns the character ID followed by the known name and alias
+func (f CharacterFile) Describe() string {
+	return describeID(f.ID, f.Name, f.Alias)
+}
+
+// Label returns the character file as shown in menus and listings
+func (f CharacterFile) Label() string {
+	return "Character ID: " + f.Describe()
+}
+
+// describeID renders "ID (Name: name, Alias: alias)", leaving out unknown parts
+func describeID(id, name, alias string) string {
+	var details []string
+	if name != "" {
+		details = append(details, "Name: "+name)
+	}
+	if alias != "" {
+		details = append(details, "Alias: "+alias)
+	}
+	if len(details) == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", id, strings.Join(details, ", "))
 }
 
 // ListUserFiles lists all user files in a profile directory
@@ -48,15 +85,9 @@ func ListUserFiles(profilePath string) ([]UserFile, error) {
 				continue
 			}
 
-			filePath := filepath.Join(profilePath, name)
-
-			// Try to extract user name (may fail for encrypted files)
-			userName, _ := TryExtractUserName(filePath)
-
 			userFiles = append(userFiles, UserFile{
 				ID:   userID,
-				Name: userName,
-				Path: filePath,
+				Path: filepath.Join(profilePath, name),
 			})
 		}
 	}
@@ -91,15 +122,9 @@ func ListCharacterFiles(profilePath string) ([]CharacterFile, error) {
 				continue
 			}
 
-			filePath := filepath.Join(profilePath, name)
-
-			// Try to extract character name (may fail for encrypted files)
-			charName, _ := TryExtractCharacterName(filePath)
-
 			charFiles = append(charFiles, CharacterFile{
 				ID:   charID,
-				Name: charName,
-				Path: filePath,
+				Path: filepath.Join(profilePath, name),
 			})
 		}
 	}
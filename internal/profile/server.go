@@ -0,0 +1,168 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Server represents an EVE server settings directory under CCP\EVE, such as
+// c_ccp_eve_online_tq_tranquility
+type Server struct {
+	Name     string // Human-readable server name, e.g. "Tranquility"
+	DirName  string
+	Path     string
+	Location string // Installation the server was found in, e.g. "Steam Proton"
+}
+
+// knownServers maps markers found in server directory names to server names
+var knownServers = []struct {
+	Marker string
+	Name   string
+	Alias  string
+}{
+	{Marker: "tranquility", Name: "Tranquility", Alias: "tq"},
+	{Marker: "singularity", Name: "Singularity", Alias: "sisi"},
+	{Marker: "thunderdome", Name: "Thunderdome", Alias: "thunderdome"},
+	{Marker: "serenity", Name: "Serenity", Alias: "serenity"},
+	{Marker: "duality", Name: "Duality", Alias: "duality"},
+}
+
+// ServerName derives a human-readable server name from a directory name.
+// Unknown servers keep their directory name.
+func ServerName(dirName string) string {
+	lower := strings.ToLower(dirName)
+	for _, s := range knownServers {
+		if strings.Contains(lower, s.Marker) {
+			return s.Name
+		}
+	}
+	return dirName
+}
+
+// ServerFromPath describes an arbitrary profiles directory as a server
+func ServerFromPath(path string) Server {
+	dirName := filepath.Base(filepath.Clean(path))
+	return Server{
+		Name:    ServerName(dirName),
+		DirName: dirName,
+		Path:    path,
+	}
+}
+
+// Matches reports whether the query names this server. The query may be the
+// server name, its directory name, its full path or a short alias such as
+// "tq" or "sisi".
+func (s Server) Matches(query string) bool {
+	if filepath.Clean(query) == filepath.Clean(s.Path) {
+		return true
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return false
+	}
+
+	if query == strings.ToLower(s.Name) || query == strings.ToLower(s.DirName) {
+		return true
+	}
+
+	for _, known := range knownServers {
+		if known.Name == s.Name && query == known.Alias {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindEVEDirectory attempts to find the CCP\EVE directory that holds one
+// settings directory per server. When several installations exist, the first
+// one found is returned; use FindInstallations to get all of them.
+func FindEVEDirectory() (string, error) {
+	installations := FindInstallations()
+	if len(installations) == 0 {
+		return "", fmt.Errorf("EVE settings directory not found (checked Windows, Steam Proton, Wine, Lutris and macOS locations)")
+	}
+
+	return installations[0].Path, nil
+}
+
+// DiscoverServers lists the server directories of every EVE installation found
+// on this machine, tagged with the installation they belong to
+func DiscoverServers() ([]Server, error) {
+	installations := FindInstallations()
+	if len(installations) == 0 {
+		return nil, fmt.Errorf("EVE settings directory not found (checked Windows, Steam Proton, Wine, Lutris and macOS locations)")
+	}
+
+	var servers []Server
+	for _, installation := range installations {
+		found, err := ListServers(installation.Path)
+		if err != nil {
+			continue
+		}
+		for _, s := range found {
+			s.Location = installation.Location
+			servers = append(servers, s)
+		}
+	}
+
+	return servers, nil
+}
+
+// ListServers lists server directories in the EVE directory. Only directories
+// that contain at least one settings_* profile are returned, which skips
+// shared cache and other non-settings directories.
+func ListServers(eveDir string) ([]Server, error) {
+	if err := ValidateProfilesDirectory(eveDir); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(eveDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EVE directory: %w", err)
+	}
+
+	var servers []Server
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		serverPath := filepath.Join(eveDir, entry.Name())
+		profiles, err := ListProfiles(serverPath)
+		if err != nil || len(profiles) == 0 {
+			continue
+		}
+
+		servers = append(servers, ServerFromPath(serverPath))
+	}
+
+	return servers, nil
+}
+
+// FindServer returns the server matching the query
+func FindServer(servers []Server, query string) (*Server, error) {
+	for i, s := range servers {
+		if s.Matches(query) {
+			return &servers[i], nil
+		}
+	}
+
+	names := make([]string, len(servers))
+	for i, s := range servers {
+		names[i] = s.Name
+	}
+	return nil, fmt.Errorf("server %q not found (available: %s)", query, strings.Join(names, ", "))
+}
+
+// Label returns the server name with its directory and, when known, the
+// installation it was found in
+func (s Server) Label() string {
+	if s.Location == "" {
+		return fmt.Sprintf("%s (%s)", s.Name, s.DirName)
+	}
+	return fmt.Sprintf("%s (%s, %s)", s.Name, s.DirName, s.Location)
+}
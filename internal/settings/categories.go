@@ -0,0 +1,212 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Category groups the settings keys that belong to one area of the client UI
+type Category struct {
+	Name        string
+	Description string
+	Keywords    []string // Lowercase substrings of matching key names
+}
+
+// Categories lists the settings categories that can be synchronized on their own
+var Categories = []Category{
+	{Name: "overview", Description: "overview tabs, columns and filter presets", Keywords: []string{"overview", "tabsettings"}},
+	{Name: "shortcuts", Description: "keyboard shortcuts", Keywords: []string{"shortcut", "hotkey", "keybind", "cmdmap"}},
+	{Name: "windows", Description: "window positions, sizes and stacks", Keywords: []string{"window", "stack", "pinned", "minimized"}},
+	{Name: "chat", Description: "chat channels and chat window settings", Keywords: []string{"chat", "channel", "lsc"}},
+	{Name: "audio", Description: "sound and music volume", Keywords: []string{"audio", "sound", "volume", "music"}},
+	{Name: "drones", Description: "drone groups and behaviour", Keywords: []string{"drone"}},
+}
+
+// maxMergeDepth is how many dict levels Merge searches for category keys.
+// Settings files keep sections at the top level and settings one level below.
+const maxMergeDepth = 3
+
+// FindCategory returns the category with the given name
+func FindCategory(name string) (Category, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range Categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
+
+// CategoryNames returns the names of all categories
+func CategoryNames() []string {
+	names := make([]string, len(Categories))
+	for i, c := range Categories {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// matches reports whether a key name belongs to the category
+func (c Category) matches(key string) bool {
+	key = strings.ToLower(key)
+	for _, keyword := range c.Keywords {
+		if strings.Contains(key, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Selection chooses which categories a merge copies from the source. An
+// empty selection copies everything, i.e. replaces the whole file.
+type Selection struct {
+	Only   []Category // Copy only these categories
+	Except []Category // Copy everything but these categories
+}
+
+// ParseSelection builds a selection from category names given as --only or
+// --except values. At most one of the lists may be set.
+func ParseSelection(only, except []string) (Selection, error) {
+	if len(only) > 0 && len(except) > 0 {
+		return Selection{}, fmt.Errorf("--only and --except cannot be combined")
+	}
+
+	var sel Selection
+	var err error
+	if sel.Only, err = parseCategories(only); err != nil {
+		return Selection{}, err
+	}
+	if sel.Except, err = parseCategories(except); err != nil {
+		return Selection{}, err
+	}
+	return sel, nil
+}
+
+func parseCategories(names []string) ([]Category, error) {
+	var categories []Category
+	seen := make(map[string]bool)
+	for _, name := range names {
+		c, ok := FindCategory(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown settings category %q (available: %s)", name, strings.Join(CategoryNames(), ", "))
+		}
+		if !seen[c.Name] {
+			seen[c.Name] = true
+			categories = append(categories, c)
+		}
+	}
+	return categories, nil
+}
+
+// IsEmpty reports whether the selection copies whole files
+func (s Selection) IsEmpty() bool {
+	return len(s.Only) == 0 && len(s.Except) == 0
+}
+
+// String describes the selection for summaries
+func (s Selection) String() string {
+	switch {
+	case len(s.Only) > 0:
+		return "only " + joinCategoryNames(s.Only)
+	case len(s.Except) > 0:
+		return "all except " + joinCategoryNames(s.Except)
+	}
+	return "all settings (whole files)"
+}
+
+func joinCategoryNames(categories []Category) string {
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func matchesAny(categories []Category, key string) bool {
+	for _, c := range categories {
+		if c.matches(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge returns a copy of target with the selected categories taken from
+// source. With Only, the target is kept and keys of the listed categories are
+// replaced by the source's; with Except, the source is taken and keys of the
+// listed categories are kept from the target. Keys missing on the side they
+// would be taken from are left as they are.
+func Merge(source, target Value, sel Selection) (Value, error) {
+	if err := checkExpandedSize(source); err != nil {
+		return nil, err
+	}
+	if err := checkExpandedSize(target); err != nil {
+		return nil, err
+	}
+
+	srcDict, ok := unwrap(source).(*Dict)
+	if !ok {
+		return nil, fmt.Errorf("source settings are not a dict")
+	}
+	tgtDict, ok := unwrap(target).(*Dict)
+	if !ok {
+		return nil, fmt.Errorf("target settings are not a dict")
+	}
+
+	switch {
+	case len(sel.Only) > 0:
+		return mergeDict(tgtDict, srcDict, sel.Only, 0), nil
+	case len(sel.Except) > 0:
+		return mergeDict(srcDict, tgtDict, sel.Except, 0), nil
+	}
+	return source, nil
+}
+
+// mergeDict keeps the entries of base, except that keys matching one of the
+// categories are taken from pick. Nested dicts present on both sides are
+// merged the same way.
+func mergeDict(base, pick *Dict, categories []Category, depth int) *Dict {
+	picked := make(map[string]Value, len(pick.Entries))
+	for _, k := range SortedKeys(pick.Entries) {
+		picked[k.Text] = k.Entry.Value
+	}
+
+	baseTexts := keyTexts(base.Entries)
+	present := make(map[string]bool, len(baseTexts))
+	for _, text := range baseTexts {
+		present[text] = true
+	}
+
+	merged := &Dict{Entries: make([]DictEntry, 0, len(base.Entries))}
+	for i, entry := range base.Entries {
+		text := baseTexts[i]
+		value := entry.Value
+		other, inPick := picked[text]
+
+		switch {
+		case matchesAny(categories, text):
+			if inPick {
+				value = other
+			}
+		case inPick && depth+1 < maxMergeDepth:
+			baseChild, ok1 := unwrap(entry.Value).(*Dict)
+			pickChild, ok2 := unwrap(other).(*Dict)
+			if ok1 && ok2 {
+				value = mergeDict(baseChild, pickChild, categories, depth+1)
+			}
+		}
+
+		merged.Entries = append(merged.Entries, DictEntry{Key: entry.Key, Value: value})
+	}
+
+	// Category keys only present in pick are added
+	for _, k := range SortedKeys(pick.Entries) {
+		if !present[k.Text] && matchesAny(categories, k.Text) {
+			merged.Entries = append(merged.Entries, DictEntry{Key: k.Entry.Key, Value: k.Entry.Value})
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,620 @@
+package settings
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"unicode/utf16"
+)
+
+// ErrTruncated is returned when the stream ends in the middle of a value
+var ErrTruncated = errors.New("unexpected end of stream")
+
+// DecodeError describes where and why decoding failed
+type DecodeError struct {
+	Offset int // Byte offset in the stream
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("marshal: offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownOpcodeError is returned for opcodes the decoder does not understand
+type UnknownOpcodeError struct {
+	Opcode byte
+}
+
+func (e *UnknownOpcodeError) Error() string {
+	return fmt.Sprintf("unknown opcode 0x%02X", e.Opcode)
+}
+
+// DecodeFile reads and decodes a core_user_*.dat or core_char_*.dat file
+func DecodeFile(path string) (Value, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	v, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+// Decode decodes a marshal stream into a value tree. Zlib-compressed streams
+// are inflated first.
+func Decode(data []byte) (Value, error) {
+	if IsCompressed(data) {
+		inflated, err := inflate(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inflate stream: %w", err)
+		}
+		data = inflated
+	}
+
+	return decodeStream(data, 0, 0)
+}
+
+// maxInflatedSize bounds decompression; settings files are a few hundred KB
+const maxInflatedSize = 16 << 20
+
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxInflatedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(inflated) > maxInflatedSize {
+		return nil, fmt.Errorf("inflated stream exceeds %d bytes", maxInflatedSize)
+	}
+	return inflated, nil
+}
+
+// decoder reads a single marshal stream
+type decoder struct {
+	data   []byte // Serialized root value, without header and shared map
+	pos    int
+	offset int // Offset of data within the outermost stream, for errors
+	depth  int
+
+	sharedMap   []int   // Slot of each shared object, in stream order
+	shared      []Value // Shared objects by slot - 1
+	sharedCount int
+}
+
+// decodeStream decodes a complete stream, including its header and shared map
+func decodeStream(data []byte, offset, depth int) (Value, error) {
+	if len(data) < 5 {
+		return nil, &DecodeError{Offset: offset, Err: ErrTruncated}
+	}
+
+	if data[0] != streamHeader {
+		return nil, &DecodeError{Offset: offset, Err: fmt.Errorf("invalid stream header 0x%02X", data[0])}
+	}
+
+	mapCount := int(binary.LittleEndian.Uint32(data[1:5]))
+	if mapCount > (len(data)-5)/4 {
+		return nil, &DecodeError{Offset: offset + 1, Err: fmt.Errorf("shared object count %d exceeds stream size", mapCount)}
+	}
+
+	mapStart := len(data) - mapCount*4
+	d := &decoder{
+		data:      data[5:mapStart],
+		offset:    offset + 5,
+		depth:     depth,
+		sharedMap: make([]int, mapCount),
+		shared:    make([]Value, mapCount),
+	}
+
+	for i := range d.sharedMap {
+		slot := int(binary.LittleEndian.Uint32(data[mapStart+i*4:]))
+		if slot < 1 || slot > mapCount {
+			return nil, &DecodeError{Offset: offset + mapStart + i*4, Err: fmt.Errorf("shared object slot %d out of range", slot)}
+		}
+		d.sharedMap[i] = slot
+	}
+
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(d.data) {
+		return nil, d.errorf("%d trailing bytes after root value", len(d.data)-d.pos)
+	}
+
+	if d.sharedCount != mapCount {
+		return nil, d.errorf("stream declares %d shared objects but stores %d", mapCount, d.sharedCount)
+	}
+
+	return v, nil
+}
+
+func (d *decoder) errorf(format string, args ...any) error {
+	return &DecodeError{Offset: d.offset + d.pos, Err: fmt.Errorf(format, args...)}
+}
+
+func (d *decoder) truncated() error {
+	return &DecodeError{Offset: d.offset + d.pos, Err: ErrTruncated}
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, d.truncated()
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, d.truncated()
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readLength reads a size: one byte, or 0xFF followed by a uint32
+func (d *decoder) readLength() (int, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != lengthExtended {
+		return int(b), nil
+	}
+
+	raw, err := d.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	n := binary.LittleEndian.Uint32(raw)
+	if n > math.MaxInt32 {
+		return 0, d.errorf("length %d too large", n)
+	}
+	return int(n), nil
+}
+
+// readCount reads the item count of a container. Every item takes at least
+// one byte, which bounds allocations for corrupt input.
+func (d *decoder) readCount() (int, error) {
+	n, err := d.readLength()
+	if err != nil {
+		return 0, err
+	}
+	if n > len(d.data)-d.pos {
+		return 0, d.truncated()
+	}
+	return n, nil
+}
+
+func (d *decoder) value() (Value, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDecodingDepth {
+		return nil, d.errorf("nesting deeper than %d levels", maxDecodingDepth)
+	}
+
+	start := d.pos
+	op, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := d.decodeOpcode(op & opcodeMask)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember the encoding so that the value can be written back unchanged
+	m := v.encoding()
+	m.op = op & opcodeMask
+	m.extended = hasLength(m.op) && d.data[start+1] == lengthExtended
+
+	if op&sharedFlag != 0 {
+		if d.sharedCount >= len(d.sharedMap) {
+			d.pos = start
+			return nil, d.errorf("more shared objects than declared")
+		}
+		m.slot = d.sharedMap[d.sharedCount]
+		d.shared[m.slot-1] = v
+		d.sharedCount++
+	}
+
+	return v, nil
+}
+
+func (d *decoder) decodeOpcode(op byte) (Value, error) {
+	switch op {
+	case opNone:
+		return &None{}, nil
+
+	case opTrue:
+		return &Bool{Value: true}, nil
+
+	case opFalse:
+		return &Bool{Value: false}, nil
+
+	case opMinusOne:
+		return &Int{Value: -1}, nil
+
+	case opZero:
+		return &Int{Value: 0}, nil
+
+	case opOne:
+		return &Int{Value: 1}, nil
+
+	case opInt8:
+		raw, err := d.readBytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return &Int{Value: int64(int8(raw[0]))}, nil
+
+	case opInt16:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return &Int{Value: int64(int16(binary.LittleEndian.Uint16(raw)))}, nil
+
+	case opInt32:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return &Int{Value: int64(int32(binary.LittleEndian.Uint32(raw)))}, nil
+
+	case opInt64:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return &Int{Value: int64(binary.LittleEndian.Uint64(raw))}, nil
+
+	case opVarInt:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		v := decodeVarInt(raw)
+		v.encoding().size = n
+		return v, nil
+
+	case opFloatZero:
+		return &Float{Value: 0}, nil
+
+	case opFloat:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return &Float{Value: math.Float64frombits(binary.LittleEndian.Uint64(raw))}, nil
+
+	case opStringEmpty:
+		return &String{}, nil
+
+	case opStringChar:
+		raw, err := d.readBytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return &String{Value: string(raw)}, nil
+
+	case opStringShort:
+		n, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.readBytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return &String{Value: string(raw)}, nil
+
+	case opStringLong:
+		raw, err := d.readSized()
+		if err != nil {
+			return nil, err
+		}
+		return &String{Value: string(raw)}, nil
+
+	case opStringTable:
+		index, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return &StringTableRef{Index: int(index)}, nil
+
+	case opGlobal:
+		raw, err := d.readSized()
+		if err != nil {
+			return nil, err
+		}
+		return &Global{Name: string(raw)}, nil
+
+	case opBuffer:
+		raw, err := d.readSized()
+		if err != nil {
+			return nil, err
+		}
+		return &Buffer{Value: append([]byte(nil), raw...)}, nil
+
+	case opUnicodeEmpty:
+		return &Unicode{}, nil
+
+	case opUnicodeChar:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return &Unicode{Value: decodeUCS2(raw)}, nil
+
+	case opUnicodeUCS2:
+		n, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if n > (len(d.data)-d.pos)/2 {
+			return nil, d.truncated()
+		}
+		raw, err := d.readBytes(n * 2)
+		if err != nil {
+			return nil, err
+		}
+		return &Unicode{Value: decodeUCS2(raw)}, nil
+
+	case opUnicodeUTF8:
+		raw, err := d.readSized()
+		if err != nil {
+			return nil, err
+		}
+		return &Unicode{Value: string(raw)}, nil
+
+	case opTupleEmpty:
+		return &Tuple{Items: []Value{}}, nil
+
+	case opTupleOne:
+		items, err := d.items(1)
+		if err != nil {
+			return nil, err
+		}
+		return &Tuple{Items: items}, nil
+
+	case opTupleTwo:
+		items, err := d.items(2)
+		if err != nil {
+			return nil, err
+		}
+		return &Tuple{Items: items}, nil
+
+	case opTuple:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		items, err := d.items(n)
+		if err != nil {
+			return nil, err
+		}
+		return &Tuple{Items: items}, nil
+
+	case opListEmpty:
+		return &List{Items: []Value{}}, nil
+
+	case opListOne:
+		items, err := d.items(1)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Items: items}, nil
+
+	case opList:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		items, err := d.items(n)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Items: items}, nil
+
+	case opDict:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		dict := &Dict{Entries: make([]DictEntry, 0, n)}
+		for i := 0; i < n; i++ {
+			// Values are stored before their keys
+			value, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			key, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			dict.Entries = append(dict.Entries, DictEntry{Key: key, Value: value})
+		}
+		return dict, nil
+
+	case opObject:
+		class, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		state, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Object{Class: class, State: state}, nil
+
+	case opObjectReduce, opObjectNew:
+		return d.objectEx(op == opObjectReduce)
+
+	case opRef:
+		slot, err := d.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if slot < 1 || slot > len(d.shared) {
+			return nil, d.errorf("reference to shared object %d out of range", slot)
+		}
+		target := d.shared[slot-1]
+		if target == nil {
+			return nil, d.errorf("reference to shared object %d before it is stored", slot)
+		}
+		return &Ref{Slot: slot, Target: target}, nil
+
+	case opChecksummed:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Checksummed{Checksum: binary.LittleEndian.Uint32(raw), Value: v}, nil
+
+	case opSubStream:
+		raw, err := d.readSized()
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeStream(raw, d.offset+d.pos-len(raw), d.depth)
+		if err != nil {
+			return nil, err
+		}
+		return &SubStream{Value: v}, nil
+	}
+
+	d.pos--
+	return nil, &DecodeError{Offset: d.offset + d.pos, Err: &UnknownOpcodeError{Opcode: op}}
+}
+
+// readSized reads a length-prefixed byte sequence
+func (d *decoder) readSized() ([]byte, error) {
+	n, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	return d.readBytes(n)
+}
+
+func (d *decoder) items(n int) ([]Value, error) {
+	items := make([]Value, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// objectEx decodes a reduce-protocol object: header, list items up to a mark,
+// then key/value pairs up to a mark
+func (d *decoder) objectEx(reduce bool) (Value, error) {
+	header, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &ObjectEx{Reduce: reduce, Header: header}
+
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.truncated()
+		}
+		if d.data[d.pos] == opMark {
+			d.pos++
+			break
+		}
+		item, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		obj.ListItems = append(obj.ListItems, item)
+	}
+
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.truncated()
+		}
+		if d.data[d.pos] == opMark {
+			d.pos++
+			break
+		}
+		key, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		obj.DictItems = append(obj.DictItems, DictEntry{Key: key, Value: value})
+	}
+
+	return obj, nil
+}
+
+// decodeVarInt decodes a little-endian two's complement integer of any size
+func decodeVarInt(raw []byte) Value {
+	if len(raw) == 0 {
+		return &Int{Value: 0}
+	}
+
+	// Convert to big-endian magnitude
+	be := make([]byte, len(raw))
+	for i, b := range raw {
+		be[len(raw)-1-i] = b
+	}
+
+	n := new(big.Int).SetBytes(be)
+	if raw[len(raw)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(raw)*8)))
+	}
+
+	if n.IsInt64() {
+		return &Int{Value: n.Int64()}
+	}
+	return &Long{Value: n}
+}
+
+// decodeUCS2 decodes little-endian UTF-16 code units
+func decodeUCS2(raw []byte) string {
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,138 @@
+package settings
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+// decodeTimeout bounds a single Decode call; fixtures decode in microseconds
+const decodeTimeout = 2 * time.Second
+
+// readFixtures returns the settings files in testdata by name. They are
+// streams with the layout of core_user_*.dat and core_char_*.dat files,
+// covering the opcodes and encodings the client writes.
+func readFixtures(t testing.TB) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	fixtures := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixtures[filepath.Base(path)] = data
+	}
+	return fixtures
+}
+
+// stream wraps a root value without shared objects in a stream header
+func stream(body ...byte) []byte {
+	data := []byte{streamHeader, 0, 0, 0, 0}
+	return append(data, body...)
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	for name, data := range readFixtures(t) {
+		if _, err := Decode(data); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for name, data := range readFixtures(t) {
+		if IsCompressed(data) {
+			continue
+		}
+		for n := 0; n < len(data); n++ {
+			_, err := Decode(data[:n])
+			if err == nil {
+				t.Errorf("%s truncated to %d bytes: decoded without error", name, n)
+			}
+		}
+	}
+}
+
+func TestDecodeUnknownOpcode(t *testing.T) {
+	for _, op := range []byte{0x00, 0x0C, 0x18, 0x30, 0x3F} {
+		_, err := Decode(stream(opList, 2, opOne, op))
+		var unknown *UnknownOpcodeError
+		if !errors.As(err, &unknown) || unknown.Opcode != op {
+			t.Errorf("opcode 0x%02X: got %v, want UnknownOpcodeError", op, err)
+		}
+
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Offset != 8 {
+			t.Errorf("opcode 0x%02X: got %v, want offset 8", op, err)
+		}
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, data := range readFixtures(f) {
+		f.Add(data)
+
+		// Truncated streams, cut at every quarter
+		for i := 1; i < 4; i++ {
+			f.Add(data[:len(data)*i/4])
+		}
+	}
+
+	// Unknown opcodes, at the root and inside containers
+	f.Add(stream(0x00))
+	f.Add(stream(0x3F))
+	f.Add(stream(opDict, 1, 0x0C, opNone))
+	f.Add(stream(opObjectReduce, opNone, 0x30, opMark, opMark))
+
+	// Lengths and counts far beyond the stream
+	huge := binary.LittleEndian.AppendUint32(nil, 0x7FFFFFFF)
+	f.Add(stream(append([]byte{opList, lengthExtended}, huge...)...))
+	f.Add(stream(append([]byte{opUnicodeUCS2, lengthExtended}, huge...)...))
+	f.Add(append([]byte{streamHeader}, huge...))
+
+	// Deep nesting, references and substreams
+	deep := make([]byte, 0, 1024)
+	for i := 0; i < 1000; i++ {
+		deep = append(deep, opListOne)
+	}
+	f.Add(stream(append(deep, opNone)...))
+	f.Add(stream(opRef, 1))
+	f.Add(stream(opSubStream, 6, streamHeader, 0, 0, 0, 0, opSubStream))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Decode runs in its own goroutine so that a hang fails the test
+		done := make(chan string, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- fmt.Sprintf("%v\n%s", r, debug.Stack())
+					return
+				}
+				done <- ""
+			}()
+			Decode(data)
+		}()
+
+		select {
+		case panicked := <-done:
+			if panicked != "" {
+				t.Fatalf("Decode of %d bytes panicked: %s", len(data), panicked)
+			}
+		case <-time.After(decodeTimeout):
+			t.Fatalf("Decode of %d bytes did not return within %v", len(data), decodeTimeout)
+		}
+	})
+}
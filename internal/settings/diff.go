@@ -0,0 +1,338 @@
+package settings
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind describes how a key path differs between two settings trees
+type ChangeKind string
+
+const (
+	// ChangeAdded means the path only exists in the second tree
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved means the path only exists in the first tree
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified means the path exists in both trees with different values
+	ChangeModified ChangeKind = "changed"
+)
+
+// Change is a single difference between two settings trees
+type Change struct {
+	Path string
+	Kind ChangeKind
+	Old  Value // Nil for added paths
+	New  Value // Nil for removed paths
+}
+
+// Diff compares two settings trees and returns their differences ordered by
+// key path. Dicts are compared by key, lists and tuples by index.
+func Diff(a, b Value) ([]Change, error) {
+	if err := checkExpandedSize(a); err != nil {
+		return nil, err
+	}
+	if err := checkExpandedSize(b); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func diffValues(path string, a, b Value, changes *[]Change) {
+	a, b = unwrap(a), unwrap(b)
+	if a == b {
+		return
+	}
+
+	switch x := a.(type) {
+	case *Dict:
+		if y, ok := b.(*Dict); ok {
+			diffEntries(path, x.Entries, y.Entries, changes)
+			return
+		}
+	case *List:
+		if y, ok := b.(*List); ok {
+			diffItems(path, x.Items, y.Items, changes)
+			return
+		}
+	case *Tuple:
+		if y, ok := b.(*Tuple); ok {
+			diffItems(path, x.Items, y.Items, changes)
+			return
+		}
+	case *Object:
+		if y, ok := b.(*Object); ok && KeyText(x.Class) == KeyText(y.Class) {
+			diffValues(joinPath(path, "state"), x.State, y.State, changes)
+			return
+		}
+	case *ObjectEx:
+		if y, ok := b.(*ObjectEx); ok {
+			diffValues(joinPath(path, "$reduce"), x.Header, y.Header, changes)
+			diffItems(joinPath(path, "items"), x.ListItems, y.ListItems, changes)
+			diffEntries(joinPath(path, "dict"), x.DictItems, y.DictItems, changes)
+			return
+		}
+	}
+
+	if !Equal(a, b) {
+		*changes = append(*changes, Change{Path: pathOrRoot(path), Kind: ChangeModified, Old: a, New: b})
+	}
+}
+
+func diffEntries(path string, a, b []DictEntry, changes *[]Change) {
+	inA := make(map[string]Value, len(a))
+	for _, k := range SortedKeys(a) {
+		inA[k.Text] = k.Entry.Value
+	}
+	inB := make(map[string]Value, len(b))
+	for _, k := range SortedKeys(b) {
+		inB[k.Text] = k.Entry.Value
+	}
+
+	keys := make([]string, 0, len(inA)+len(inB))
+	for k := range inA {
+		keys = append(keys, k)
+	}
+	for k := range inB {
+		if _, ok := inA[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+
+	for _, k := range keys {
+		entryPath := joinPath(path, k)
+		valueA, okA := inA[k]
+		valueB, okB := inB[k]
+		switch {
+		case !okB:
+			*changes = append(*changes, Change{Path: entryPath, Kind: ChangeRemoved, Old: unwrap(valueA)})
+		case !okA:
+			*changes = append(*changes, Change{Path: entryPath, Kind: ChangeAdded, New: unwrap(valueB)})
+		default:
+			diffValues(entryPath, valueA, valueB, changes)
+		}
+	}
+}
+
+func diffItems(path string, a, b []Value, changes *[]Change) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(b):
+			*changes = append(*changes, Change{Path: itemPath, Kind: ChangeRemoved, Old: unwrap(a[i])})
+		case i >= len(a):
+			*changes = append(*changes, Change{Path: itemPath, Kind: ChangeAdded, New: unwrap(b[i])})
+		default:
+			diffValues(itemPath, a[i], b[i], changes)
+		}
+	}
+}
+
+// unwrap strips references and containers that do not carry settings
+func unwrap(v Value) Value {
+	for {
+		switch t := Resolve(v).(type) {
+		case *SubStream:
+			v = t.Value
+		case *Checksummed:
+			v = t.Value
+		default:
+			return t
+		}
+	}
+}
+
+// joinPath appends a dict key to a key path, quoting keys that contain path
+// separators
+func joinPath(path, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\"") {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// Equal reports whether two settings trees hold the same data, as references
+// and the values they point to do. Byte strings equal unicode strings whose
+// UTF-8 encoding has the same bytes, ints equal longs of the same value;
+// every other scalar only equals one of its own type and value.
+func Equal(a, b Value) bool {
+	a, b = unwrap(a), unwrap(b)
+	if a == b {
+		return true
+	}
+
+	switch x := a.(type) {
+	case *None:
+		_, ok := b.(*None)
+		return ok
+	case *Bool:
+		y, ok := b.(*Bool)
+		return ok && x.Value == y.Value
+	case *Int, *Long:
+		m, okA := integer(a)
+		n, okB := integer(b)
+		return okA && okB && m.Cmp(n) == 0
+	case *Float:
+		y, ok := b.(*Float)
+		if !ok {
+			return false
+		}
+		return x.Value == y.Value || (math.IsNaN(x.Value) && math.IsNaN(y.Value))
+	case *String, *Unicode:
+		m, okA := stringBytes(a)
+		n, okB := stringBytes(b)
+		return okA && okB && m == n
+	case *Buffer:
+		y, ok := b.(*Buffer)
+		return ok && bytes.Equal(x.Value, y.Value)
+	case *Global:
+		y, ok := b.(*Global)
+		return ok && x.Name == y.Name
+	case *StringTableRef:
+		y, ok := b.(*StringTableRef)
+		return ok && x.Index == y.Index
+	case *Tuple:
+		y, ok := b.(*Tuple)
+		return ok && equalItems(x.Items, y.Items)
+	case *List:
+		y, ok := b.(*List)
+		return ok && equalItems(x.Items, y.Items)
+	case *Dict:
+		y, ok := b.(*Dict)
+		return ok && equalDicts(x.Entries, y.Entries)
+	case *Object:
+		y, ok := b.(*Object)
+		return ok && Equal(x.Class, y.Class) && Equal(x.State, y.State)
+	case *ObjectEx:
+		y, ok := b.(*ObjectEx)
+		return ok && x.Reduce == y.Reduce && Equal(x.Header, y.Header) &&
+			equalItems(x.ListItems, y.ListItems) && equalEntries(x.DictItems, y.DictItems)
+	}
+	return false
+}
+
+// integer returns the value of an int or long
+func integer(v Value) (*big.Int, bool) {
+	switch t := v.(type) {
+	case *Int:
+		return big.NewInt(t.Value), true
+	case *Long:
+		return t.Value, t.Value != nil
+	}
+	return nil, false
+}
+
+// stringBytes returns the bytes of a byte string, or the UTF-8 encoding of a
+// unicode string
+func stringBytes(v Value) (string, bool) {
+	switch t := v.(type) {
+	case *String:
+		return t.Value, true
+	case *Unicode:
+		return t.Value, true
+	}
+	return "", false
+}
+
+func equalItems(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalDicts compares dicts regardless of entry order. Keys are matched by
+// their text and must be equal themselves, so that a byte string key does
+// not equal an int key that renders the same.
+func equalDicts(a, b []DictEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keysA, keysB := SortedKeys(a), SortedKeys(b)
+	for i := range keysA {
+		x, y := keysA[i], keysB[i]
+		if x.Text != y.Text || !Equal(x.Entry.Key, y.Entry.Key) || !Equal(x.Entry.Value, y.Entry.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalEntries compares entries in order, as ObjectEx dict items are replayed
+func equalEntries(a, b []DictEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i].Key, b[i].Key) || !Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatValue renders a value as compact single-line JSON
+func FormatValue(v Value) string {
+	if v == nil {
+		return ""
+	}
+	raw, err := marshalJSON(plain(unwrap(v)))
+	if err != nil {
+		return KeyText(v)
+	}
+	return string(raw)
+}
+
+// ByteRange is a run of bytes that differs between two files
+type ByteRange struct {
+	Offset int
+	Old    []byte
+	New    []byte
+}
+
+// DiffBytes compares two files byte by byte and returns the runs of
+// differing bytes. It is the fallback when a file cannot be decoded.
+func DiffBytes(a, b []byte) []ByteRange {
+	var ranges []ByteRange
+
+	common := min(len(a), len(b))
+	for i := 0; i < common; {
+		if a[i] == b[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < common && a[i] != b[i] {
+			i++
+		}
+		ranges = append(ranges, ByteRange{Offset: start, Old: a[start:i], New: b[start:i]})
+	}
+
+	if len(a) != len(b) {
+		ranges = append(ranges, ByteRange{Offset: common, Old: a[common:], New: b[common:]})
+	}
+
+	return ranges
+}
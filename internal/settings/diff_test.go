@@ -0,0 +1,75 @@
+package settings
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestEqualScalars(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Value
+		want bool
+	}{
+		{"different invalid UTF-8 bytes", &String{Value: "\xff"}, &String{Value: "\xfe"}, false},
+		{"same invalid UTF-8 bytes", &String{Value: "Zo\xeb"}, &String{Value: "Zo\xeb"}, true},
+		{"byte string and unicode with the same bytes", &String{Value: "Zoë"}, &Unicode{Value: "Zoë"}, true},
+		{"cp1252 byte string and unicode", &String{Value: "Zo\xeb"}, &Unicode{Value: "Zoë"}, false},
+		{"NaN", &Float{Value: math.NaN()}, &Float{Value: math.NaN()}, true},
+		{"NaN and zero", &Float{Value: math.NaN()}, &Float{Value: 0}, false},
+		{"int and long of the same value", &Int{Value: 42}, &Long{Value: big.NewInt(42)}, true},
+		{"long and int of another value", &Long{Value: big.NewInt(43)}, &Int{Value: 42}, false},
+		{"int and float", &Int{Value: 1}, &Float{Value: 1}, false},
+		{"int and bool", &Int{Value: 1}, &Bool{Value: true}, false},
+		{"byte string and int", &String{Value: "1"}, &Int{Value: 1}, false},
+		{"byte string and buffer", &String{Value: "ab"}, &Buffer{Value: []byte("ab")}, false},
+		{"globals", &Global{Name: "a.b"}, &Global{Name: "a.c"}, false},
+		{"string table references", &StringTableRef{Index: 1}, &StringTableRef{Index: 1}, true},
+		{"none", &None{}, &None{}, true},
+		{"dict keys of different types", &Dict{Entries: []DictEntry{{Key: &String{Value: "1"}, Value: &None{}}}},
+			&Dict{Entries: []DictEntry{{Key: &Int{Value: 1}, Value: &None{}}}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Equal = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Equal(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s: reversed Equal = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffReportsCp1252Names(t *testing.T) {
+	renamed := &Dict{Entries: []DictEntry{
+		{Key: &String{Value: "chat"}, Value: &String{Value: "Zo\xe9"}},
+		{Key: &String{Value: "window"}, Value: &Unicode{Value: "Zoë"}},
+		{Key: &String{Value: "caf\xe9"}, Value: &Buffer{Value: []byte{0xCA, 0xFE}}},
+	}}
+
+	changes, err := Diff(cp1252Names(), renamed)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "chat" || changes[0].Kind != ChangeModified {
+		t.Fatalf("Diff = %+v, want chat changed", changes)
+	}
+
+	if changes, _ := Diff(cp1252Names(), cp1252Names()); len(changes) != 0 {
+		t.Errorf("Diff of equal trees = %+v", changes)
+	}
+}
+
+func TestDiffIntAndLong(t *testing.T) {
+	a := &List{Items: []Value{&Int{Value: 7}, &Long{Value: big.NewInt(8)}}}
+	b := &List{Items: []Value{&Long{Value: big.NewInt(7)}, &Int{Value: 9}}}
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "[1]" {
+		t.Errorf("Diff = %+v, want only [1] changed", changes)
+	}
+}
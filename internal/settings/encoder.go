@@ -0,0 +1,546 @@
+package settings
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf16"
+)
+
+// ErrRoundTrip is returned when a settings file does not encode back to the
+// bytes it was decoded from
+var ErrRoundTrip = errors.New("settings file does not round-trip through the encoder")
+
+// Encode serializes a value tree into a marshal stream. Decoded values are
+// written with the opcodes, length forms and shared object slots they were
+// read with, so an unmodified tree encodes to the original bytes. Values
+// created in code, or changed so that their original opcode no longer fits,
+// get the most compact encoding.
+func Encode(v Value) ([]byte, error) {
+	e := &encoder{
+		slots:  make(map[Value]int),
+		stored: make(map[Value]bool),
+	}
+
+	// Find the shared objects first: their count precedes the root value
+	var shared []Value
+	if err := collectShared(v, make(map[Value]bool), &shared, 0); err != nil {
+		return nil, err
+	}
+	e.assignSlots(shared)
+
+	e.buf.WriteByte(streamHeader)
+	e.writeUint32(uint32(len(shared)))
+
+	if err := e.value(v, 0); err != nil {
+		return nil, err
+	}
+
+	for _, s := range shared {
+		e.writeUint32(uint32(e.slots[s]))
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// CheckRoundTrip decodes a settings file and encodes it again, returning
+// ErrRoundTrip if the result differs from the original. Files that fail the
+// check must not be rewritten: the encoder would silently change them.
+// Compressed files are compared after inflating.
+func CheckRoundTrip(data []byte) error {
+	v, err := Decode(data)
+	if err != nil {
+		return err
+	}
+
+	if IsCompressed(data) {
+		if data, err = inflate(data); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := Encode(v)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(encoded, data) {
+		return fmt.Errorf("%w: first difference at offset %d", ErrRoundTrip, firstDifference(encoded, data))
+	}
+
+	return nil
+}
+
+func firstDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
+
+// IsCompressed reports whether a settings file is stored zlib-compressed
+func IsCompressed(data []byte) bool {
+	return len(data) > 0 && data[0] == 0x78
+}
+
+// Deflate zlib-compresses an encoded stream
+func Deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// children returns the values nested in v in the order they are serialized.
+// Substreams are separate streams and have no children here.
+func children(v Value) []Value {
+	switch t := v.(type) {
+	case *Tuple:
+		return t.Items
+	case *List:
+		return t.Items
+	case *Dict:
+		items := make([]Value, 0, len(t.Entries)*2)
+		for _, entry := range t.Entries {
+			items = append(items, entry.Value, entry.Key)
+		}
+		return items
+	case *Object:
+		return []Value{t.Class, t.State}
+	case *ObjectEx:
+		items := append([]Value{t.Header}, t.ListItems...)
+		for _, entry := range t.DictItems {
+			items = append(items, entry.Key, entry.Value)
+		}
+		return items
+	case *Checksummed:
+		return []Value{t.Value}
+	}
+	return nil
+}
+
+// collectShared lists the shared objects of a tree in the order the encoder
+// stores them: after their children, skipping values already stored, which
+// are written as references
+func collectShared(v Value, stored map[Value]bool, shared *[]Value, depth int) error {
+	if depth >= maxDecodingDepth {
+		return fmt.Errorf("marshal: nesting deeper than %d levels", maxDecodingDepth)
+	}
+
+	if ref, ok := v.(*Ref); ok {
+		if ref.Target == nil {
+			return fmt.Errorf("marshal: unresolved reference to shared object %d", ref.Slot)
+		}
+		if stored[ref.Target] {
+			return nil
+		}
+		// The referenced object is not part of this tree; write it in full
+		return collectShared(ref.Target, stored, shared, depth+1)
+	}
+
+	if v == nil || stored[v] {
+		return nil
+	}
+
+	for _, child := range children(v) {
+		if err := collectShared(child, stored, shared, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if v.encoding().slot > 0 {
+		stored[v] = true
+		*shared = append(*shared, v)
+	}
+
+	return nil
+}
+
+// assignSlots keeps the decoded slots of the shared objects when they still
+// form a valid slot map and numbers them in storing order otherwise
+func (e *encoder) assignSlots(shared []Value) {
+	used := make(map[int]bool, len(shared))
+	valid := true
+	for _, s := range shared {
+		slot := s.encoding().slot
+		if slot < 1 || slot > len(shared) || used[slot] {
+			valid = false
+			break
+		}
+		used[slot] = true
+	}
+
+	for i, s := range shared {
+		if valid {
+			e.slots[s] = s.encoding().slot
+		} else {
+			e.slots[s] = i + 1
+		}
+	}
+}
+
+// encoder writes a single marshal stream
+type encoder struct {
+	buf    bytes.Buffer
+	slots  map[Value]int  // Slot of every shared object
+	stored map[Value]bool // Shared objects written so far
+}
+
+func (e *encoder) writeLength(n int, extended bool) {
+	if n < lengthExtended && !extended {
+		e.buf.WriteByte(byte(n))
+		return
+	}
+	e.buf.WriteByte(lengthExtended)
+	e.writeUint32(uint32(n))
+}
+
+func (e *encoder) writeUint32(n uint32) {
+	var raw [4]byte
+	binary.LittleEndian.PutUint32(raw[:], n)
+	e.buf.Write(raw[:])
+}
+
+func (e *encoder) writeSized(op byte, m *meta, data []byte) {
+	e.buf.WriteByte(op)
+	e.writeLength(len(data), m.extended)
+	e.buf.Write(data)
+}
+
+func (e *encoder) writeRef(slot int, m *meta) {
+	e.buf.WriteByte(opRef)
+	e.writeLength(slot, m.extended)
+}
+
+func (e *encoder) value(v Value, depth int) error {
+	if depth >= maxDecodingDepth {
+		return fmt.Errorf("marshal: nesting deeper than %d levels", maxDecodingDepth)
+	}
+
+	if ref, ok := v.(*Ref); ok {
+		if e.stored[ref.Target] {
+			e.writeRef(e.slots[ref.Target], ref.encoding())
+			return nil
+		}
+		return e.value(ref.Target, depth+1)
+	}
+
+	if v == nil {
+		return fmt.Errorf("marshal: cannot encode nil value")
+	}
+
+	// A shared object that occurs again is written as a reference
+	if e.stored[v] {
+		e.writeRef(e.slots[v], &meta{})
+		return nil
+	}
+
+	start := e.buf.Len()
+	if err := e.encodeValue(v, depth); err != nil {
+		return err
+	}
+
+	if slot, ok := e.slots[v]; ok && slot > 0 {
+		e.buf.Bytes()[start] |= sharedFlag
+		e.stored[v] = true
+	}
+
+	return nil
+}
+
+func (e *encoder) items(items []Value, depth int) error {
+	for _, item := range items {
+		if err := e.value(item, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeValue writes a value without its shared flag
+func (e *encoder) encodeValue(v Value, depth int) error {
+	m := v.encoding()
+
+	switch t := v.(type) {
+	case *None:
+		e.buf.WriteByte(opNone)
+
+	case *Bool:
+		if t.Value {
+			e.buf.WriteByte(opTrue)
+		} else {
+			e.buf.WriteByte(opFalse)
+		}
+
+	case *Int:
+		e.int(t.Value, m)
+
+	case *Long:
+		e.writeSized(opVarInt, m, encodeVarInt(t.Value, m.size))
+
+	case *Float:
+		if math.Float64bits(t.Value) == 0 && m.op != opFloat {
+			e.buf.WriteByte(opFloatZero)
+			break
+		}
+		e.buf.WriteByte(opFloat)
+		var raw [8]byte
+		binary.LittleEndian.PutUint64(raw[:], math.Float64bits(t.Value))
+		e.buf.Write(raw[:])
+
+	case *String:
+		e.string(t.Value, m)
+
+	case *Unicode:
+		e.unicode(t.Value, m)
+
+	case *Buffer:
+		e.writeSized(opBuffer, m, t.Value)
+
+	case *Global:
+		e.writeSized(opGlobal, m, []byte(t.Name))
+
+	case *StringTableRef:
+		if t.Index < 0 || t.Index > 0xFF {
+			return fmt.Errorf("marshal: string table index %d out of range", t.Index)
+		}
+		e.buf.WriteByte(opStringTable)
+		e.buf.WriteByte(byte(t.Index))
+
+	case *Tuple:
+		n := len(t.Items)
+		switch {
+		case n == 0 && m.op != opTuple:
+			e.buf.WriteByte(opTupleEmpty)
+		case n == 1 && m.op != opTuple:
+			e.buf.WriteByte(opTupleOne)
+		case n == 2 && m.op != opTuple:
+			e.buf.WriteByte(opTupleTwo)
+		default:
+			e.buf.WriteByte(opTuple)
+			e.writeLength(n, m.extended)
+		}
+		return e.items(t.Items, depth)
+
+	case *List:
+		n := len(t.Items)
+		switch {
+		case n == 0 && m.op != opList:
+			e.buf.WriteByte(opListEmpty)
+		case n == 1 && m.op != opList:
+			e.buf.WriteByte(opListOne)
+		default:
+			e.buf.WriteByte(opList)
+			e.writeLength(n, m.extended)
+		}
+		return e.items(t.Items, depth)
+
+	case *Dict:
+		e.buf.WriteByte(opDict)
+		e.writeLength(len(t.Entries), m.extended)
+		for _, entry := range t.Entries {
+			// Values are stored before their keys
+			if err := e.value(entry.Value, depth+1); err != nil {
+				return err
+			}
+			if err := e.value(entry.Key, depth+1); err != nil {
+				return err
+			}
+		}
+
+	case *Object:
+		e.buf.WriteByte(opObject)
+		if err := e.value(t.Class, depth+1); err != nil {
+			return err
+		}
+		return e.value(t.State, depth+1)
+
+	case *ObjectEx:
+		if t.Reduce {
+			e.buf.WriteByte(opObjectReduce)
+		} else {
+			e.buf.WriteByte(opObjectNew)
+		}
+		if err := e.value(t.Header, depth+1); err != nil {
+			return err
+		}
+		if err := e.items(t.ListItems, depth); err != nil {
+			return err
+		}
+		e.buf.WriteByte(opMark)
+		for _, entry := range t.DictItems {
+			if err := e.value(entry.Key, depth+1); err != nil {
+				return err
+			}
+			if err := e.value(entry.Value, depth+1); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(opMark)
+
+	case *SubStream:
+		inner, err := Encode(t.Value)
+		if err != nil {
+			return err
+		}
+		e.writeSized(opSubStream, m, inner)
+
+	case *Checksummed:
+		e.buf.WriteByte(opChecksummed)
+		e.writeUint32(t.Checksum)
+		return e.value(t.Value, depth+1)
+
+	default:
+		return fmt.Errorf("marshal: cannot encode %T", v)
+	}
+
+	return nil
+}
+
+// int writes an integer with its original opcode if the value still fits,
+// otherwise with the smallest opcode that holds it
+func (e *encoder) int(n int64, m *meta) {
+	op := m.op
+	if !intFits(op, n) {
+		op = smallestIntOpcode(n)
+	}
+
+	switch op {
+	case opMinusOne, opZero, opOne:
+		e.buf.WriteByte(op)
+	case opInt8:
+		e.buf.WriteByte(opInt8)
+		e.buf.WriteByte(byte(int8(n)))
+	case opInt16:
+		e.buf.WriteByte(opInt16)
+		var raw [2]byte
+		binary.LittleEndian.PutUint16(raw[:], uint16(int16(n)))
+		e.buf.Write(raw[:])
+	case opInt32:
+		e.buf.WriteByte(opInt32)
+		e.writeUint32(uint32(int32(n)))
+	case opVarInt:
+		e.writeSized(opVarInt, m, encodeVarInt(big.NewInt(n), m.size))
+	default:
+		e.buf.WriteByte(opInt64)
+		var raw [8]byte
+		binary.LittleEndian.PutUint64(raw[:], uint64(n))
+		e.buf.Write(raw[:])
+	}
+}
+
+// intFits reports whether an integer opcode can hold n
+func intFits(op byte, n int64) bool {
+	switch op {
+	case opMinusOne:
+		return n == -1
+	case opZero:
+		return n == 0
+	case opOne:
+		return n == 1
+	case opInt8:
+		return n >= math.MinInt8 && n <= math.MaxInt8
+	case opInt16:
+		return n >= math.MinInt16 && n <= math.MaxInt16
+	case opInt32:
+		return n >= math.MinInt32 && n <= math.MaxInt32
+	case opInt64, opVarInt:
+		return true
+	}
+	return false
+}
+
+func smallestIntOpcode(n int64) byte {
+	for _, op := range []byte{opMinusOne, opZero, opOne, opInt8, opInt16, opInt32} {
+		if intFits(op, n) {
+			return op
+		}
+	}
+	return opInt64
+}
+
+// string writes a byte string with its original opcode if it still fits
+func (e *encoder) string(s string, m *meta) {
+	n := len(s)
+	switch {
+	case n == 0 && m.op != opStringShort && m.op != opStringLong:
+		e.buf.WriteByte(opStringEmpty)
+	case n == 1 && (m.op == 0 || m.op == opStringChar || m.op == opStringEmpty):
+		e.buf.WriteByte(opStringChar)
+		e.buf.WriteString(s)
+	case n < 256 && m.op != opStringLong:
+		e.buf.WriteByte(opStringShort)
+		e.buf.WriteByte(byte(n))
+		e.buf.WriteString(s)
+	default:
+		e.writeSized(opStringLong, m, []byte(s))
+	}
+}
+
+// unicode writes a unicode string with its original opcode if it still fits
+func (e *encoder) unicode(s string, m *meta) {
+	units := utf16.Encode([]rune(s))
+	switch {
+	case len(units) == 0 && m.op != opUnicodeUCS2 && m.op != opUnicodeUTF8:
+		e.buf.WriteByte(opUnicodeEmpty)
+	case len(units) == 1 && (m.op == 0 || m.op == opUnicodeChar || m.op == opUnicodeEmpty):
+		e.buf.WriteByte(opUnicodeChar)
+		var raw [2]byte
+		binary.LittleEndian.PutUint16(raw[:], units[0])
+		e.buf.Write(raw[:])
+	case m.op == opUnicodeUCS2:
+		e.buf.WriteByte(opUnicodeUCS2)
+		e.writeLength(len(units), m.extended)
+		raw := make([]byte, len(units)*2)
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(raw[i*2:], u)
+		}
+		e.buf.Write(raw)
+	default:
+		e.writeSized(opUnicodeUTF8, m, []byte(s))
+	}
+}
+
+// encodeVarInt encodes an integer as little-endian two's complement using as
+// few bytes as possible, sign-extended to size bytes if size is larger
+func encodeVarInt(n *big.Int, size int) []byte {
+	length := 0
+	if n.Sign() != 0 {
+		length = n.BitLen()/8 + 1
+	}
+	length = max(length, size)
+
+	v := new(big.Int).Set(n)
+	if v.Sign() < 0 {
+		v.Add(v, new(big.Int).Lsh(big.NewInt(1), uint(length*8)))
+	}
+
+	be := v.FillBytes(make([]byte, length))
+	raw := make([]byte, length)
+	for i, b := range be {
+		raw[length-1-i] = b
+	}
+
+	// Drop redundant sign bytes beyond the requested size
+	for len(raw) > max(size, 1) {
+		last, next := raw[len(raw)-1], raw[len(raw)-2]
+		if (last == 0x00 && next&0x80 == 0) || (last == 0xFF && next&0x80 != 0) {
+			raw = raw[:len(raw)-1]
+			continue
+		}
+		break
+	}
+
+	return raw
+}
@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeRoundTripsFixtures(t *testing.T) {
+	for name, data := range readFixtures(t) {
+		v, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Compressed files are compared after inflating
+		want := data
+		if IsCompressed(data) {
+			if want, err = inflate(data); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		encoded, err := Encode(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(encoded, want) {
+			t.Errorf("%s: encoded stream differs at offset %d", name, firstDifference(encoded, want))
+		}
+
+		if err := CheckRoundTrip(data); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestCheckRoundTripRejectsLossyStream(t *testing.T) {
+	// A UCS-2 string with an unpaired surrogate decodes to U+FFFD, which
+	// encodes to different bytes
+	data := stream(opUnicodeUCS2, 1, 0x00, 0xD8)
+	if _, err := Decode(data); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if err := CheckRoundTrip(data); !errors.Is(err, ErrRoundTrip) {
+		t.Errorf("CheckRoundTrip = %v, want ErrRoundTrip", err)
+	}
+}
@@ -0,0 +1,68 @@
+package settings
+
+// Marshal stream layout: a header byte, the number of shared objects as a
+// little-endian uint32, the serialized root value, and finally one uint32 slot
+// index per shared object.
+const (
+	streamHeader = 0x7E // '~'
+
+	opcodeMask = 0x3F // Low bits select the opcode
+	sharedFlag = 0x40 // Object is stored in the shared object map
+
+	lengthExtended   = 0xFF // Length byte followed by a uint32 length
+	maxDecodingDepth = 512
+)
+
+// Opcodes of CCP's marshal format
+const (
+	opNone         = 0x01
+	opGlobal       = 0x02
+	opInt64        = 0x03
+	opInt32        = 0x04
+	opInt16        = 0x05
+	opInt8         = 0x06
+	opMinusOne     = 0x07
+	opZero         = 0x08
+	opOne          = 0x09
+	opFloat        = 0x0A
+	opFloatZero    = 0x0B
+	opBuffer       = 0x0D
+	opStringEmpty  = 0x0E
+	opStringChar   = 0x0F
+	opStringShort  = 0x10
+	opStringTable  = 0x11
+	opUnicodeUCS2  = 0x12
+	opStringLong   = 0x13
+	opTuple        = 0x14
+	opList         = 0x15
+	opDict         = 0x16
+	opObject       = 0x17
+	opRef          = 0x1B
+	opChecksummed  = 0x1C
+	opTrue         = 0x1F
+	opFalse        = 0x20
+	opObjectReduce = 0x22
+	opObjectNew    = 0x23
+	opTupleEmpty   = 0x24
+	opTupleOne     = 0x25
+	opListEmpty    = 0x26
+	opListOne      = 0x27
+	opUnicodeEmpty = 0x28
+	opUnicodeChar  = 0x29
+	opSubStream    = 0x2B
+	opTupleTwo     = 0x2C
+	opMark         = 0x2D // Terminates the list and dict items of ObjectEx
+	opUnicodeUTF8  = 0x2E
+	opVarInt       = 0x2F
+)
+
+// hasLength reports whether an opcode is followed by a length or count in the
+// one byte / 0xFF + uint32 form
+func hasLength(op byte) bool {
+	switch op {
+	case opGlobal, opBuffer, opUnicodeUCS2, opStringLong, opTuple, opList, opDict,
+		opRef, opSubStream, opUnicodeUTF8, opVarInt:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,386 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// orderedMap is a JSON/YAML object with a fixed key order
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]any)}
+}
+
+// MarshalJSON writes the keys in order
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalJSON is json.Marshal without HTML escaping, which would turn the
+// "<buffer:...>" markers into \u003c sequences
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// plain converts a value tree into JSON-friendly Go values. Dict keys are
+// rendered as text and sorted so the output is stable between saves.
+func plain(v Value) any {
+	switch t := v.(type) {
+	case *None:
+		return nil
+	case *Bool:
+		return t.Value
+	case *Int:
+		return t.Value
+	case *Long:
+		return json.Number(t.Value.String())
+	case *Float:
+		if math.IsNaN(t.Value) || math.IsInf(t.Value, 0) {
+			return strconv.FormatFloat(t.Value, 'g', -1, 64)
+		}
+		return t.Value
+	case *String:
+		return text(t.Value)
+	case *Unicode:
+		return text(t.Value)
+	case *Buffer:
+		return "<buffer:" + hex.EncodeToString(t.Value) + ">"
+	case *Global:
+		return "<global:" + t.Name + ">"
+	case *StringTableRef:
+		return fmt.Sprintf("<stringtable:%d>", t.Index)
+	case *Tuple:
+		return plainItems(t.Items)
+	case *List:
+		return plainItems(t.Items)
+	case *Dict:
+		return plainEntries(t.Entries)
+	case *Object:
+		m := newOrderedMap()
+		m.set("$class", KeyText(t.Class))
+		m.set("state", plain(t.State))
+		return m
+	case *ObjectEx:
+		m := newOrderedMap()
+		m.set("$reduce", plain(t.Header))
+		if len(t.ListItems) > 0 {
+			m.set("items", plainItems(t.ListItems))
+		}
+		if len(t.DictItems) > 0 {
+			m.set("dict", plainEntries(t.DictItems))
+		}
+		return m
+	case *SubStream:
+		return plain(t.Value)
+	case *Checksummed:
+		return plain(t.Value)
+	case *Ref:
+		return plain(t.Target)
+	}
+	return nil
+}
+
+func plainItems(items []Value) []any {
+	result := make([]any, len(items))
+	for i, item := range items {
+		result[i] = plain(item)
+	}
+	return result
+}
+
+func plainEntries(entries []DictEntry) *orderedMap {
+	m := newOrderedMap()
+	for _, key := range SortedKeys(entries) {
+		m.set(key.Text, plain(key.Entry.Value))
+	}
+	return m
+}
+
+// KeyText renders a dict key as text: strings as-is, numbers in decimal and
+// tuples as "(a, b)"
+func KeyText(v Value) string {
+	switch t := Resolve(v).(type) {
+	case *None:
+		return "None"
+	case *Bool:
+		if t.Value {
+			return "True"
+		}
+		return "False"
+	case *Int:
+		return strconv.FormatInt(t.Value, 10)
+	case *Long:
+		return t.Value.String()
+	case *Float:
+		return strconv.FormatFloat(t.Value, 'g', -1, 64)
+	case *String:
+		return text(t.Value)
+	case *Unicode:
+		return text(t.Value)
+	case *Buffer:
+		return hex.EncodeToString(t.Value)
+	case *Global:
+		return t.Name
+	case *StringTableRef:
+		return fmt.Sprintf("<stringtable:%d>", t.Index)
+	case *Tuple:
+		return "(" + keyTextItems(t.Items) + ")"
+	case *List:
+		return "[" + keyTextItems(t.Items) + "]"
+	case *SubStream:
+		return KeyText(t.Value)
+	case *Checksummed:
+		return KeyText(t.Value)
+	}
+	return fmt.Sprintf("<%T>", v)
+}
+
+// text renders a string as-is if it is valid UTF-8. Other byte strings, like
+// names saved in a Windows code page, are rendered as "<bytes:hex>" so that
+// JSON does not replace their bytes and YAML can encode them at all.
+func text(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return "<bytes:" + hex.EncodeToString([]byte(s)) + ">"
+}
+
+func keyTextItems(items []Value) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = KeyText(item)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SortedKey is a dict entry with its key rendered as text
+type SortedKey struct {
+	Text  string
+	Entry DictEntry
+}
+
+// SortedKeys returns the entries of a dict ordered by key text, numeric keys
+// first in numeric order. Keys that render identically get a "#n" suffix.
+func SortedKeys(entries []DictEntry) []SortedKey {
+	texts := keyTexts(entries)
+	keys := make([]SortedKey, len(entries))
+	for i, e := range entries {
+		keys[i] = SortedKey{Text: texts[i], Entry: e}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keyLess(keys[i].Text, keys[j].Text)
+	})
+
+	return keys
+}
+
+// keyTexts renders the keys of a dict in entry order. Keys that render
+// identically get a "#n" suffix.
+func keyTexts(entries []DictEntry) []string {
+	texts := make([]string, len(entries))
+	seen := make(map[string]int, len(entries))
+	for i, e := range entries {
+		text := KeyText(e.Key)
+		seen[text]++
+		if n := seen[text]; n > 1 {
+			text = fmt.Sprintf("%s #%d", text, n)
+		}
+		texts[i] = text
+	}
+	return texts
+}
+
+// keyLess orders numeric keys first in numeric order, then text keys
+func keyLess(a, b string) bool {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return x < y
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
+
+// maxExpandedValues bounds the size of a tree with every reference expanded.
+// References can point at objects full of references, so a small crafted file
+// could otherwise expand into billions of values.
+const maxExpandedValues = 1 << 22
+
+// ErrTooLarge is returned for trees that expand to more than maxExpandedValues
+var ErrTooLarge = errors.New("settings tree too large")
+
+// checkExpandedSize fails if the tree expands to too many values
+func checkExpandedSize(v Value) error {
+	if n := expandedSize(v, make(map[Value]int)); n > maxExpandedValues {
+		return fmt.Errorf("%w: expands to more than %d values", ErrTooLarge, maxExpandedValues)
+	}
+	return nil
+}
+
+// expandedSize counts the values of a tree with references expanded. Counting
+// stops just above maxExpandedValues. Only shared objects can be reached more
+// than once, so only their sizes are memoized.
+func expandedSize(v Value, memo map[Value]int) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case *Ref:
+		return expandedSize(t.Target, memo)
+	case *SubStream:
+		return 1 + expandedSize(t.Value, memo)
+	}
+
+	if n, ok := memo[v]; ok {
+		return n
+	}
+
+	n := 1
+	for _, child := range children(v) {
+		n += expandedSize(child, memo)
+		if n > maxExpandedValues {
+			break
+		}
+	}
+
+	if v.encoding().slot > 0 {
+		memo[v] = n
+	}
+	return n
+}
+
+// WriteJSON writes the value tree as indented JSON
+func WriteJSON(w io.Writer, v Value) error {
+	if err := checkExpandedSize(v); err != nil {
+		return err
+	}
+
+	raw, err := marshalJSON(plain(v))
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return fmt.Errorf("failed to indent JSON: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteYAML writes the value tree as YAML
+func WriteYAML(w io.Writer, v Value) error {
+	if err := checkExpandedSize(v); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(yamlNode(plain(v))); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// yamlNode converts a plain value into a YAML node, keeping key order and
+// number formatting
+func yamlNode(v any) *yaml.Node {
+	switch t := v.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(t, 10)}
+	case json.Number:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: t.String()}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(t, 'g', -1, 64)}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: flowStyle(t)}
+		for _, item := range t {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case *orderedMap:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, k := range t.keys {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k},
+				yamlNode(t.values[k]))
+		}
+		return node
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+}
+
+// flowStyle keeps short sequences of scalars, like window positions, on one line
+func flowStyle(items []any) yaml.Style {
+	if len(items) == 0 || len(items) > 8 {
+		return 0
+	}
+	for _, item := range items {
+		switch item.(type) {
+		case []any, *orderedMap:
+			return 0
+		}
+	}
+	return yaml.FlowStyle
+}
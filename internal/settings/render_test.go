@@ -0,0 +1,63 @@
+package settings
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// cp1252Names holds names as the client saves them in a Windows code page,
+// next to the same names in UTF-8
+func cp1252Names() Value {
+	return &Dict{Entries: []DictEntry{
+		{Key: &String{Value: "chat"}, Value: &String{Value: "Zo\xeb"}},
+		{Key: &String{Value: "window"}, Value: &Unicode{Value: "Zoë"}},
+		{Key: &String{Value: "caf\xe9"}, Value: &Buffer{Value: []byte{0xCA, 0xFE}}},
+	}}
+}
+
+func TestWriteJSONKeepsInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, cp1252Names()); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`"chat": "<bytes:5a6feb>"`, `"window": "Zoë"`, `"<bytes:636166e9>": "<buffer:cafe>"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("JSON lacks %s:\n%s", want, out)
+		}
+	}
+	if strings.ContainsRune(out, '\uFFFD') {
+		t.Errorf("JSON replaced bytes with U+FFFD:\n%s", out)
+	}
+}
+
+func TestWriteYAMLKeepsInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, cp1252Names()); err != nil {
+		t.Fatalf("WriteYAML: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"chat: <bytes:5a6feb>", "window: Zoë", "<bytes:636166e9>: <buffer:cafe>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("YAML lacks %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteFixtures(t *testing.T) {
+	for name, data := range readFixtures(t) {
+		v, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := WriteJSON(&bytes.Buffer{}, v); err != nil {
+			t.Errorf("%s: WriteJSON: %v", name, err)
+		}
+		if err := WriteYAML(&bytes.Buffer{}, v); err != nil {
+			t.Errorf("%s: WriteYAML: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package settings
+
+import "math/big"
+
+// Value is a node of a decoded settings tree. The concrete types mirror the
+// Python objects CCP's marshal format can serialize.
+type Value interface {
+	isValue()
+	encoding() *meta
+}
+
+// meta records how a decoded value was serialized, so that an unmodified tree
+// encodes back to the same bytes. The zero value selects the most compact
+// encoding, which is what values created in code get.
+type meta struct {
+	op       byte // Opcode without the shared flag, 0 if not decoded
+	extended bool // Length was stored as 0xFF followed by a uint32
+	size     int  // Byte length of a variable length integer
+	slot     int  // Shared object slot, 0 if the value is not shared
+}
+
+func (m *meta) encoding() *meta { return m }
+
+// None is Python's None
+type None struct {
+	meta
+}
+
+// Bool is a Python bool
+type Bool struct {
+	meta
+	Value bool
+}
+
+// Int is a Python int that fits into 64 bits
+type Int struct {
+	meta
+	Value int64
+}
+
+// Long is an arbitrary precision integer stored as a variable length integer
+type Long struct {
+	meta
+	Value *big.Int
+}
+
+// Float is a Python float
+type Float struct {
+	meta
+	Value float64
+}
+
+// String is a Python byte string; settings keys are usually stored this way
+type String struct {
+	meta
+	Value string
+}
+
+// Unicode is a Python unicode string
+type Unicode struct {
+	meta
+	Value string
+}
+
+// Buffer is raw binary data
+type Buffer struct {
+	meta
+	Value []byte
+}
+
+// Global references a Python global by name, e.g. a class used by an Object
+type Global struct {
+	meta
+	Name string
+}
+
+// StringTableRef references an entry of the client's built-in string table,
+// which is not part of the file
+type StringTableRef struct {
+	meta
+	Index int
+}
+
+// Tuple is a Python tuple
+type Tuple struct {
+	meta
+	Items []Value
+}
+
+// List is a Python list
+type List struct {
+	meta
+	Items []Value
+}
+
+// Dict is a Python dict. Entries keep the order they were stored in.
+type Dict struct {
+	meta
+	Entries []DictEntry
+}
+
+// DictEntry is a single key/value pair of a Dict
+type DictEntry struct {
+	Key   Value
+	Value Value
+}
+
+// Object is an instance created from a class name and its state
+type Object struct {
+	meta
+	Class Value
+	State Value
+}
+
+// ObjectEx is an object serialized through Python's reduce protocol: a header
+// (callable and arguments) followed by list items and dict items
+type ObjectEx struct {
+	meta
+	Reduce    bool // Created via __reduce__ rather than __newobj__
+	Header    Value
+	ListItems []Value
+	DictItems []DictEntry
+}
+
+// SubStream is a complete marshal stream embedded in another one
+type SubStream struct {
+	meta
+	Value Value
+}
+
+// Checksummed is a value preceded by a checksum of its serialized form
+type Checksummed struct {
+	meta
+	Checksum uint32
+	Value    Value
+}
+
+// Ref is a reference to a shared object stored earlier in the stream
+type Ref struct {
+	meta
+	Slot   int   // 1-based index into the shared object map
+	Target Value // The referenced object
+}
+
+func (*None) isValue()           {}
+func (*Bool) isValue()           {}
+func (*Int) isValue()            {}
+func (*Long) isValue()           {}
+func (*Float) isValue()          {}
+func (*String) isValue()         {}
+func (*Unicode) isValue()        {}
+func (*Buffer) isValue()         {}
+func (*Global) isValue()         {}
+func (*StringTableRef) isValue() {}
+func (*Tuple) isValue()          {}
+func (*List) isValue()           {}
+func (*Dict) isValue()           {}
+func (*Object) isValue()         {}
+func (*ObjectEx) isValue()       {}
+func (*SubStream) isValue()      {}
+func (*Checksummed) isValue()    {}
+func (*Ref) isValue()            {}
+
+// Resolve follows references and returns the value they point to
+func Resolve(v Value) Value {
+	for {
+		ref, ok := v.(*Ref)
+		if !ok || ref.Target == nil {
+			return v
+		}
+		v = ref.Target
+	}
+}
+
+// Get returns the value stored under a string key, or nil if the dict has no
+// such key. Both byte string and unicode keys match.
+func (d *Dict) Get(key string) Value {
+	for _, e := range d.Entries {
+		if k, ok := KeyString(e.Key); ok && k == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// KeyString returns the text of a String or Unicode value
+func KeyString(v Value) (string, bool) {
+	switch k := Resolve(v).(type) {
+	case *String:
+		return k.Value, true
+	case *Unicode:
+		return k.Value, true
+	}
+	return "", false
+}
@@ -0,0 +1,151 @@
+package sync
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PlanProfileCopy builds a plan for copying every file of the source profile
+// into the destination profile, which may not exist yet
+func PlanProfileCopy(sourceProfilePath, destProfilePath string) (*Plan, error) {
+	if err := ValidateProfilePath(sourceProfilePath); err != nil {
+		return nil, fmt.Errorf("source profile validation failed: %w", err)
+	}
+
+	plan := &Plan{
+		Kind:        "profile",
+		ProfilePath: destProfilePath,
+		Source:      sourceProfilePath,
+	}
+
+	err := filepath.WalkDir(sourceProfilePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceProfilePath, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		plan.SourceSize += int64(len(content))
+
+		entry := PlanEntry{
+			Name:    relPath,
+			Path:    filepath.Join(destProfilePath, relPath),
+			Action:  ActionReplace,
+			content: content,
+		}
+
+		info, err := os.Stat(entry.Path)
+		switch {
+		case os.IsNotExist(err):
+			entry.Reason = "new file"
+		case err != nil:
+			return fmt.Errorf("failed to stat %s: %w", entry.Path, err)
+		case info.IsDir():
+			entry.Action = ActionSkip
+			entry.Reason = "not a regular file"
+		default:
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+
+			same, err := sameContent(entry.Path, info.Size(), content)
+			if err != nil {
+				return fmt.Errorf("failed to compare file %s: %w", relPath, err)
+			}
+			if same {
+				entry.Action = ActionUnchanged
+				entry.Reason = "identical to source"
+			}
+		}
+
+		plan.Entries = append(plan.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan profile copy: %w", err)
+	}
+
+	if len(plan.Entries) == 0 {
+		return nil, fmt.Errorf("source profile is empty: %s", sourceProfilePath)
+	}
+
+	return plan, nil
+}
+
+// CopyProfile copies every file of the source profile into the destination
+// profile, e.g. from the Tranquility directory into the Singularity one.
+// Files that exist only in the destination are kept. Either all files are
+// copied or the destination is left as it was.
+func CopyProfile(sourceProfilePath, destProfilePath string) error {
+	plan, err := PlanProfileCopy(sourceProfilePath, destProfilePath)
+	if err != nil {
+		return err
+	}
+
+	// Create missing directories, remembering them for cleanup on failure
+	var createdDirs []string
+	for _, e := range plan.Entries {
+		if e.Action != ActionReplace {
+			continue
+		}
+		created, err := mkdirAllTracked(filepath.Dir(e.Path))
+		createdDirs = append(createdDirs, created...)
+		if err != nil {
+			removeDirs(createdDirs)
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	if err := Apply(plan); err != nil {
+		removeDirs(createdDirs)
+		return err
+	}
+
+	return nil
+}
+
+// mkdirAllTracked creates dir and any missing parents, returning the
+// directories it created from outermost to innermost
+func mkdirAllTracked(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	var created []string
+	for _, d := range missing {
+		if err := os.Mkdir(d, 0755); err != nil && !os.IsExist(err) {
+			return created, err
+		}
+		created = append(created, d)
+	}
+
+	return created, nil
+}
+
+// removeDirs removes directories created for a failed copy, innermost first.
+// Directories that are not empty are left alone.
+func removeDirs(dirs []string) {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+}
@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"eve-profile-sync/internal/settings"
+)
+
+// MergePlan turns a whole-file replacement plan into a category merge: every
+// file to replace keeps its own settings except the selected categories,
+// which are taken from the source. Files whose selected categories already
+// match the source are marked unchanged.
+func MergePlan(plan *Plan, sel settings.Selection) error {
+	if sel.IsEmpty() {
+		return nil
+	}
+
+	// Only files the encoder reproduces byte for byte are safe to rewrite
+	if err := settings.CheckRoundTrip(plan.content); err != nil {
+		return fmt.Errorf("cannot merge from source %s file %s: %w", plan.Kind, plan.Source, err)
+	}
+
+	source, err := settings.Decode(plan.content)
+	if err != nil {
+		return fmt.Errorf("failed to decode source %s file %s: %w", plan.Kind, plan.Source, err)
+	}
+
+	for i := range plan.Entries {
+		entry := &plan.Entries[i]
+		if entry.Action != ActionReplace {
+			continue
+		}
+
+		content, err := mergeFile(entry.Path, source, sel)
+		if err != nil {
+			return fmt.Errorf("failed to merge %s: %w", entry.Name, err)
+		}
+
+		if content == nil {
+			entry.Action = ActionUnchanged
+			entry.Reason = "selected settings already match"
+			continue
+		}
+
+		entry.content = content
+		entry.Reason = "merge " + sel.String()
+	}
+
+	return nil
+}
+
+// mergeFile returns the merged content for a target file, or nil if merging
+// would not change it
+func mergeFile(path string, source settings.Value, sel settings.Selection) ([]byte, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := settings.CheckRoundTrip(existing); err != nil {
+		return nil, fmt.Errorf("refusing to rewrite: %w", err)
+	}
+
+	target, err := settings.Decode(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := settings.Merge(source, target, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Equal(merged, target) {
+		return nil, nil
+	}
+
+	content, err := settings.Encode(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the new content reads back as the merged settings
+	written, err := settings.Decode(content)
+	if err != nil {
+		return nil, fmt.Errorf("merged settings do not decode: %w", err)
+	}
+	if !settings.Equal(written, merged) {
+		return nil, fmt.Errorf("merged settings do not decode to the merged tree")
+	}
+
+	if settings.IsCompressed(existing) {
+		if content, err = settings.Deflate(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if bytes.Equal(content, existing) {
+		return nil, nil
+	}
+
+	return content, nil
+}
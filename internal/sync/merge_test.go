@@ -0,0 +1,168 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eve-profile-sync/internal/settings"
+)
+
+// Marshal opcodes used to build settings streams
+const (
+	opTupleTwo    = 0x2C
+	opInt8        = 0x06
+	opStringShort = 0x10
+	opUnicodeUCS2 = 0x12
+	opDict        = 0x16
+)
+
+func str(s string) []byte {
+	return append([]byte{opStringShort, byte(len(s))}, s...)
+}
+
+// settingsFile builds a stream with window and audio settings. extra is
+// appended to the root dict as the value of a "name" entry when set.
+func settingsFile(x, y, volume byte, extra []byte) []byte {
+	entries := byte(2)
+	if extra != nil {
+		entries++
+	}
+
+	data := []byte{0x7E, 0, 0, 0, 0, opDict, entries}
+	data = append(data, opDict, 1, opTupleTwo, opInt8, x, opInt8, y)
+	data = append(data, str("overview")...)
+	data = append(data, str("windows")...)
+	data = append(data, opDict, 1, opInt8, volume)
+	data = append(data, str("volume")...)
+	data = append(data, str("audio")...)
+	if extra != nil {
+		data = append(data, extra...)
+		data = append(data, str("name")...)
+	}
+	return data
+}
+
+// windowNameFile builds a stream whose windows category holds a name, as
+// the client saves it as a byte string in the Windows code page
+func windowNameFile(name string) []byte {
+	data := []byte{0x7E, 0, 0, 0, 0, opDict, 2}
+	data = append(data, opDict, 1)
+	data = append(data, str(name)...)
+	data = append(data, str("caption")...)
+	data = append(data, str("windows")...)
+	data = append(data, opDict, 1, opInt8, 90)
+	data = append(data, str("volume")...)
+	data = append(data, str("audio")...)
+	return data
+}
+
+// mergeProfile writes source and target user and character files into a new
+// profile directory
+func mergeProfile(t *testing.T, source, target []byte) (dir, sourceUser, sourceChar string) {
+	t.Helper()
+	dir = t.TempDir()
+	files := map[string][]byte{
+		"core_user_1.dat":  source,
+		"core_user_2.dat":  target,
+		"core_char_10.dat": source,
+		"core_char_11.dat": target,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, filepath.Join(dir, "core_user_1.dat"), filepath.Join(dir, "core_char_10.dat")
+}
+
+func windowsOnly(t *testing.T) settings.Selection {
+	t.Helper()
+	sel, err := settings.ParseSelection([]string{"windows"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
+
+func TestSyncMergesSelectedCategories(t *testing.T) {
+	source := settingsFile(10, 20, 50, nil)
+	dir, sourceUser, sourceChar := mergeProfile(t, source, settingsFile(1, 2, 90, nil))
+
+	if err := SyncProfile(dir, sourceUser, sourceChar, Options{Categories: windowsOnly(t)}); err != nil {
+		t.Fatalf("SyncProfile: %v", err)
+	}
+
+	// Windows come from the source, audio stays as it was
+	want := settingsFile(10, 20, 90, nil)
+	for _, name := range []string{"core_user_2.dat", "core_char_11.dat"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s = % X, want % X", name, got, want)
+		}
+	}
+}
+
+func TestSyncMergesCp1252Names(t *testing.T) {
+	// Both names are invalid UTF-8 and only differ in the last byte
+	source := windowNameFile("Zo\xe9")
+	dir, sourceUser, sourceChar := mergeProfile(t, source, windowNameFile("Zo\xeb"))
+
+	if err := SyncProfile(dir, sourceUser, sourceChar, Options{Categories: windowsOnly(t)}); err != nil {
+		t.Fatalf("SyncProfile: %v", err)
+	}
+
+	for _, name := range []string{"core_user_2.dat", "core_char_11.dat"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(source) {
+			t.Errorf("%s = % X, want % X", name, got, source)
+		}
+	}
+}
+
+func TestSyncRefusesToMergeLossyTarget(t *testing.T) {
+	// An unpaired surrogate does not survive decoding and encoding
+	lossy := settingsFile(1, 2, 90, []byte{opUnicodeUCS2, 1, 0x00, 0xD8})
+	dir, sourceUser, sourceChar := mergeProfile(t, settingsFile(10, 20, 50, nil), lossy)
+
+	err := SyncProfile(dir, sourceUser, sourceChar, Options{Categories: windowsOnly(t)})
+	if !errors.Is(err, settings.ErrRoundTrip) {
+		t.Fatalf("SyncProfile = %v, want ErrRoundTrip", err)
+	}
+
+	for _, name := range []string{"core_user_2.dat", "core_char_11.dat"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(lossy) {
+			t.Errorf("%s was rewritten", name)
+		}
+	}
+}
+
+func TestSyncRefusesToMergeLossySource(t *testing.T) {
+	lossy := settingsFile(10, 20, 50, []byte{opUnicodeUCS2, 1, 0x00, 0xD8})
+	target := settingsFile(1, 2, 90, nil)
+	dir, sourceUser, sourceChar := mergeProfile(t, lossy, target)
+
+	err := SyncProfile(dir, sourceUser, sourceChar, Options{Categories: windowsOnly(t)})
+	if !errors.Is(err, settings.ErrRoundTrip) {
+		t.Fatalf("SyncProfile = %v, want ErrRoundTrip", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "core_user_2.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(target) {
+		t.Error("core_user_2.dat was rewritten")
+	}
+}
@@ -0,0 +1,202 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eve-profile-sync/internal/profile"
+)
+
+// Action describes what synchronization will do with a file
+type Action string
+
+const (
+	// ActionReplace means the file content will be overwritten
+	ActionReplace Action = "replace"
+	// ActionUnchanged means the file already matches the source byte-for-byte
+	ActionUnchanged Action = "unchanged"
+	// ActionSkip means the file is left alone; see PlanEntry.Reason
+	ActionSkip Action = "skip"
+)
+
+// PlanEntry describes a single file considered for replacement
+type PlanEntry struct {
+	Name    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Action  Action
+	Reason  string // Empty for replaced files
+
+	content []byte // Overrides the plan content when set
+}
+
+// Plan describes which files a replacement would touch without writing anything
+type Plan struct {
+	Kind        string // "user" or "character"
+	ProfilePath string
+	Source      string
+	SourceSize  int64
+	Entries     []PlanEntry
+
+	content  []byte
+	filtered bool // Some entries were skipped by a target filter
+}
+
+// Count returns the number of entries with the given action
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, e := range p.Entries {
+		if e.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// PlanUserFiles builds a replacement plan for the user files in the profile
+// allowed by the target filter
+func PlanUserFiles(profilePath, sourceUserFile string, targets TargetFilter) (*Plan, error) {
+	return planFiles("user", "core_user_", profile.ExtractUserID, profilePath, sourceUserFile, targets)
+}
+
+// PlanCharacterFiles builds a replacement plan for the character files in the
+// profile allowed by the target filter
+func PlanCharacterFiles(profilePath, sourceCharFile string, targets TargetFilter) (*Plan, error) {
+	return planFiles("character", "core_char_", profile.ExtractCharacterID, profilePath, sourceCharFile, targets)
+}
+
+// PlanProfile builds the user and character file plans for one target profile,
+// merging only the selected settings categories when the options ask for it
+func PlanProfile(profilePath, sourceUserFile, sourceCharFile string, opts Options) (*Plan, *Plan, error) {
+	userPlan, err := PlanUserFiles(profilePath, sourceUserFile, opts.UserTargets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	charPlan, err := PlanCharacterFiles(profilePath, sourceCharFile, opts.CharacterTargets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := MergePlan(userPlan, opts.Categories); err != nil {
+		return nil, nil, err
+	}
+
+	if err := MergePlan(charPlan, opts.Categories); err != nil {
+		return nil, nil, err
+	}
+
+	return userPlan, charPlan, nil
+}
+
+func planFiles(kind, prefix string, extractID func(string) (string, error), profilePath, sourceFile string, targets TargetFilter) (*Plan, error) {
+	// Read source file content
+	sourceContent, err := os.ReadFile(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source %s file: %w", kind, err)
+	}
+
+	// Stat source file to exclude it from replacement; the source may live in
+	// another profile, where a file with the same name is a regular target
+	sourceInfo, err := os.Stat(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat source %s file: %w", kind, err)
+	}
+
+	// List all files in profile directory
+	entries, err := os.ReadDir(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile directory: %w", err)
+	}
+
+	plan := &Plan{
+		Kind:        kind,
+		ProfilePath: profilePath,
+		Source:      sourceFile,
+		SourceSize:  int64(len(sourceContent)),
+		content:     sourceContent,
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		// Check if file matches pattern {prefix}*.dat
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".dat") {
+			continue
+		}
+
+		planEntry := PlanEntry{
+			Name: name,
+			Path: filepath.Join(profilePath, name),
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file %s: %w", name, err)
+		}
+		planEntry.Size = info.Size()
+		planEntry.ModTime = info.ModTime()
+
+		switch {
+		case entry.IsDir():
+			planEntry.Action = ActionSkip
+			planEntry.Reason = "not a regular file"
+		case os.SameFile(info, sourceInfo):
+			planEntry.Action = ActionSkip
+			planEntry.Reason = "source file"
+		case !targets.IsEmpty() && !allowed(targets, extractID, name, &planEntry):
+			plan.filtered = true
+		default:
+			same, err := sameContent(planEntry.Path, info.Size(), sourceContent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare file %s: %w", name, err)
+			}
+			if same {
+				planEntry.Action = ActionUnchanged
+				planEntry.Reason = "identical to source"
+			} else {
+				planEntry.Action = ActionReplace
+			}
+		}
+
+		plan.Entries = append(plan.Entries, planEntry)
+	}
+
+	return plan, nil
+}
+
+// sameContent reports whether the file at path holds exactly the given content
+func sameContent(path string, size int64, content []byte) (bool, error) {
+	if size != int64(len(content)) {
+		return false, nil
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(existing, content), nil
+}
+
+// allowed applies the target filter to a file, marking the entry as skipped
+// when the file is not a target
+func allowed(targets TargetFilter, extractID func(string) (string, error), name string, entry *PlanEntry) bool {
+	id, err := extractID(name)
+	if err != nil {
+		entry.Action = ActionSkip
+		entry.Reason = "unrecognized filename"
+		return false
+	}
+
+	ok, reason := targets.Allows(id)
+	if !ok {
+		entry.Action = ActionSkip
+		entry.Reason = reason
+	}
+	return ok
+}
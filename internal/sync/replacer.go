@@ -4,106 +4,26 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
-// ReplaceUserFiles replaces all user files with the selected user file content
-func ReplaceUserFiles(profilePath, sourceUserFile string) error {
-	// Read source file content
-	sourceContent, err := os.ReadFile(sourceUserFile)
+// ReplaceUserFiles replaces the targeted user files with the selected user file content
+func ReplaceUserFiles(profilePath, sourceUserFile string, targets TargetFilter) error {
+	plan, err := PlanUserFiles(profilePath, sourceUserFile, targets)
 	if err != nil {
-		return fmt.Errorf("failed to read source user file: %w", err)
+		return err
 	}
 
-	// Get source filename to exclude it from replacement
-	sourceFilename := filepath.Base(sourceUserFile)
-
-	// List all files in profile directory
-	entries, err := os.ReadDir(profilePath)
-	if err != nil {
-		return fmt.Errorf("failed to read profile directory: %w", err)
-	}
-
-	var replacedCount int
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		// Check if file matches pattern core_user_*.dat
-		if strings.HasPrefix(name, "core_user_") && strings.HasSuffix(name, ".dat") {
-			// Skip source file itself
-			if name == sourceFilename {
-				continue
-			}
-
-			filePath := filepath.Join(profilePath, name)
-
-			// Write source content to target file
-			if err := os.WriteFile(filePath, sourceContent, 0644); err != nil {
-				return fmt.Errorf("failed to replace file %s: %w", name, err)
-			}
-
-			replacedCount++
-		}
-	}
-
-	if replacedCount == 0 {
-		return fmt.Errorf("no user files found to replace")
-	}
-
-	return nil
+	return Apply(plan)
 }
 
-// ReplaceCharacterFiles replaces all character files with the selected character file content
-func ReplaceCharacterFiles(profilePath, sourceCharFile string) error {
-	// Read source file content
-	sourceContent, err := os.ReadFile(sourceCharFile)
+// ReplaceCharacterFiles replaces the targeted character files with the selected character file content
+func ReplaceCharacterFiles(profilePath, sourceCharFile string, targets TargetFilter) error {
+	plan, err := PlanCharacterFiles(profilePath, sourceCharFile, targets)
 	if err != nil {
-		return fmt.Errorf("failed to read source character file: %w", err)
+		return err
 	}
 
-	// Get source filename to exclude it from replacement
-	sourceFilename := filepath.Base(sourceCharFile)
-
-	// List all files in profile directory
-	entries, err := os.ReadDir(profilePath)
-	if err != nil {
-		return fmt.Errorf("failed to read profile directory: %w", err)
-	}
-
-	var replacedCount int
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		// Check if file matches pattern core_char_*.dat
-		if strings.HasPrefix(name, "core_char_") && strings.HasSuffix(name, ".dat") {
-			// Skip source file itself
-			if name == sourceFilename {
-				continue
-			}
-
-			filePath := filepath.Join(profilePath, name)
-
-			// Write source content to target file
-			if err := os.WriteFile(filePath, sourceContent, 0644); err != nil {
-				return fmt.Errorf("failed to replace file %s: %w", name, err)
-			}
-
-			replacedCount++
-		}
-	}
-
-	if replacedCount == 0 {
-		return fmt.Errorf("no character files found to replace")
-	}
-
-	return nil
+	return Apply(plan)
 }
 
 // ValidateProfilePath validates that a profile path exists and is accessible
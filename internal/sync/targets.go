@@ -0,0 +1,35 @@
+package sync
+
+// TargetFilter limits which files receive the source content, by user or character ID
+type TargetFilter struct {
+	Include []string // When non-empty, only these IDs are replaced
+	Exclude []string // These IDs are never replaced
+}
+
+// IsEmpty reports whether the filter allows every file
+func (f TargetFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Allows reports whether the file with the given ID is a target.
+// When it is not, the returned reason explains why.
+func (f TargetFilter) Allows(id string) (bool, string) {
+	if contains(f.Exclude, id) {
+		return false, "excluded from targets"
+	}
+
+	if len(f.Include) > 0 && !contains(f.Include, id) {
+		return false, "not selected as target"
+	}
+
+	return true, ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
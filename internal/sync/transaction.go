@@ -0,0 +1,266 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eve-profile-sync/internal/settings"
+)
+
+// renameFile moves a file into place; tests replace it to inject failures
+var renameFile = os.Rename
+
+// pendingWrite is a single file replacement inside a transaction
+type pendingWrite struct {
+	path     string
+	content  []byte
+	tempPath string // Staged new content
+	origPath string // Preserved original content
+	created  bool   // Target did not exist before the transaction
+	renamed  bool   // Staged file has been moved into place
+}
+
+// Transaction replaces a set of files all-or-nothing: new content is staged
+// next to each target first and only renamed into place once every write
+// succeeded. If any step fails, all originals are restored.
+type Transaction struct {
+	writes []*pendingWrite
+}
+
+// NewTransaction creates an empty transaction
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// AddPlan adds every file the plan marks for replacement
+func (t *Transaction) AddPlan(plan *Plan) {
+	for _, e := range plan.Entries {
+		if e.Action != ActionReplace {
+			continue
+		}
+		content := e.content
+		if content == nil {
+			content = plan.content
+		}
+		t.writes = append(t.writes, &pendingWrite{path: e.Path, content: content})
+	}
+}
+
+// Add adds a single file write
+func (t *Transaction) Add(path string, content []byte) {
+	t.writes = append(t.writes, &pendingWrite{path: path, content: content})
+}
+
+// Commit stages all writes, moves them into place and rolls back on failure
+func (t *Transaction) Commit() error {
+	// Step 1: Stage new content in temp files
+	for _, w := range t.writes {
+		tempPath, err := stageFile(w.path, w.content)
+		if err != nil {
+			t.cleanup()
+			return fmt.Errorf("failed to stage %s: %w", filepath.Base(w.path), err)
+		}
+		w.tempPath = tempPath
+	}
+
+	// Step 2: Preserve originals so they can be restored
+	for _, w := range t.writes {
+		origPath, err := preserveOriginal(w.path)
+		if err != nil {
+			t.cleanup()
+			return fmt.Errorf("failed to preserve original %s: %w", filepath.Base(w.path), err)
+		}
+		w.origPath = origPath
+		w.created = origPath == ""
+	}
+
+	// Step 3: Move staged files into place
+	for _, w := range t.writes {
+		if err := renameFile(w.tempPath, w.path); err != nil {
+			commitErr := fmt.Errorf("failed to replace file %s: %w", filepath.Base(w.path), err)
+			if rollbackErr := t.rollback(); rollbackErr != nil {
+				return errors.Join(commitErr, rollbackErr)
+			}
+			return commitErr
+		}
+		w.renamed = true
+	}
+
+	// Step 4: Drop preserved originals
+	t.cleanup()
+
+	return nil
+}
+
+// rollback restores the original content of every file already replaced and
+// removes files the transaction created
+func (t *Transaction) rollback() error {
+	var failed []string
+	for i := len(t.writes) - 1; i >= 0; i-- {
+		w := t.writes[i]
+		if !w.renamed {
+			continue
+		}
+		if w.created {
+			if err := os.Remove(w.path); err != nil {
+				failed = append(failed, w.path+" (new file, remove manually)")
+				continue
+			}
+			w.renamed = false
+			continue
+		}
+		if err := renameFile(w.origPath, w.path); err != nil {
+			failed = append(failed, w.origPath)
+			continue
+		}
+		w.origPath = ""
+		w.renamed = false
+	}
+
+	if len(failed) > 0 {
+		// Keep the preserved copies on disk so they can be restored by hand
+		for _, w := range t.writes {
+			if w.tempPath != "" {
+				os.Remove(w.tempPath)
+			}
+		}
+		return fmt.Errorf("rollback failed, originals kept at: %s", strings.Join(failed, ", "))
+	}
+
+	t.cleanup()
+	return nil
+}
+
+// cleanup removes staged and preserved files left by the transaction
+func (t *Transaction) cleanup() {
+	for _, w := range t.writes {
+		if w.tempPath != "" && !w.renamed {
+			os.Remove(w.tempPath)
+		}
+		if w.origPath != "" {
+			os.Remove(w.origPath)
+		}
+		w.tempPath = ""
+		w.origPath = ""
+	}
+}
+
+// stageFile writes content to a temp file next to path and flushes it to disk.
+// The temp file gets the permissions of the file it will replace.
+func stageFile(path string, content []byte) (string, error) {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".sync-*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// preserveOriginal keeps a copy of the file at path, using a hard link when
+// the filesystem supports it and a full copy otherwise. It returns an empty
+// path when there is no original to preserve.
+func preserveOriginal(path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	origPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".sync-orig")
+	os.Remove(origPath)
+
+	if err := os.Link(path, origPath); err == nil {
+		return origPath, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(origPath, content, info.Mode().Perm()); err != nil {
+		os.Remove(origPath)
+		return "", err
+	}
+
+	return origPath, nil
+}
+
+// Apply replaces the files of all plans in a single transaction
+func Apply(plans ...*Plan) error {
+	tx := NewTransaction()
+	for _, plan := range plans {
+		// A filter that deselects every file is a deliberate choice, not an error
+		if plan.Count(ActionReplace)+plan.Count(ActionUnchanged) == 0 && !plan.filtered {
+			return fmt.Errorf("no %s files found to replace in %s", plan.Kind, plan.ProfilePath)
+		}
+		tx.AddPlan(plan)
+	}
+
+	return tx.Commit()
+}
+
+// Options controls which files a synchronization touches
+type Options struct {
+	UserTargets      TargetFilter
+	CharacterTargets TargetFilter
+	Categories       settings.Selection // Empty to replace whole files
+}
+
+// SyncProfile replaces the targeted user and character files in the profile with
+// the selected ones. Either every file is replaced or the profile is left untouched.
+func SyncProfile(profilePath, sourceUserFile, sourceCharFile string, opts Options) error {
+	return SyncProfiles([]string{profilePath}, sourceUserFile, sourceCharFile, opts)
+}
+
+// SyncProfiles replaces the targeted user and character files in every target
+// profile with the selected ones. The source files may come from any profile.
+// All profiles are planned before anything is written and replaced in a single
+// transaction, so a failure leaves every profile untouched.
+func SyncProfiles(profilePaths []string, sourceUserFile, sourceCharFile string, opts Options) error {
+	var plans []*Plan
+	for _, profilePath := range profilePaths {
+		userPlan, charPlan, err := PlanProfile(profilePath, sourceUserFile, sourceCharFile, opts)
+		if err != nil {
+			return err
+		}
+
+		plans = append(plans, userPlan, charPlan)
+	}
+
+	return Apply(plans...)
+}
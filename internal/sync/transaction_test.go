@@ -0,0 +1,165 @@
+package sync
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeFiles creates files with the given content in dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertDir checks that dir holds exactly the given files with the given
+// content, so no staged or preserved files are left behind
+func assertDir(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	var wantNames []string
+	for name := range want {
+		wantNames = append(wantNames, name)
+	}
+	sort.Strings(wantNames)
+	if len(names) != len(wantNames) {
+		t.Fatalf("directory holds %v, want %v", names, wantNames)
+	}
+	for i := range names {
+		if names[i] != wantNames[i] {
+			t.Fatalf("directory holds %v, want %v", names, wantNames)
+		}
+	}
+
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, []byte(content)) {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
+
+// failRenameAt makes the n-th call of renameFile fail for the rest of the test
+func failRenameAt(t *testing.T, n int) {
+	t.Helper()
+	calls := 0
+	renameFile = func(from, to string) error {
+		calls++
+		if calls == n {
+			return errors.New("injected failure")
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+}
+
+func TestCommitReplacesAllFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"core_user_1.dat": "user 1",
+		"core_char_2.dat": "char 2",
+	})
+
+	tx := NewTransaction()
+	tx.Add(filepath.Join(dir, "core_user_1.dat"), []byte("new user"))
+	tx.Add(filepath.Join(dir, "core_char_2.dat"), []byte("new char"))
+	tx.Add(filepath.Join(dir, "core_char_3.dat"), []byte("created"))
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	assertDir(t, dir, map[string]string{
+		"core_user_1.dat": "new user",
+		"core_char_2.dat": "new char",
+		"core_char_3.dat": "created",
+	})
+}
+
+func TestCommitRollsBackFailedRename(t *testing.T) {
+	original := map[string]string{
+		"core_user_1.dat": "user 1",
+		"core_char_2.dat": "char 2",
+		"core_char_4.dat": "char 4",
+	}
+
+	// Every position of the failing rename, including the first and the last
+	for n := 1; n <= 4; n++ {
+		dir := t.TempDir()
+		writeFiles(t, dir, original)
+
+		tx := NewTransaction()
+		tx.Add(filepath.Join(dir, "core_user_1.dat"), []byte("new user"))
+		tx.Add(filepath.Join(dir, "core_char_3.dat"), []byte("created"))
+		tx.Add(filepath.Join(dir, "core_char_2.dat"), []byte("new char 2"))
+		tx.Add(filepath.Join(dir, "core_char_4.dat"), []byte("new char 4"))
+
+		failRenameAt(t, n)
+		if err := tx.Commit(); err == nil {
+			t.Fatalf("rename %d: Commit succeeded despite the failure", n)
+		}
+		renameFile = os.Rename
+
+		// Originals are back byte for byte, the created file is gone and no
+		// .sync-orig or temp files are left
+		assertDir(t, dir, original)
+	}
+}
+
+func TestCommitKeepsOriginalsWhenRollbackFails(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"core_user_1.dat": "user 1",
+		"core_char_2.dat": "char 2",
+	})
+
+	tx := NewTransaction()
+	tx.Add(filepath.Join(dir, "core_user_1.dat"), []byte("new user"))
+	tx.Add(filepath.Join(dir, "core_char_2.dat"), []byte("new char"))
+
+	// The second rename fails and so does restoring the first file
+	failing := map[int]bool{2: true, 3: true}
+	calls := 0
+	renameFile = func(from, to string) error {
+		calls++
+		if failing[calls] {
+			return errors.New("injected failure")
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { renameFile = os.Rename }()
+
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Commit succeeded despite the failure")
+	}
+
+	// The preserved original stays on disk for a manual restore
+	preserved, err := os.ReadFile(filepath.Join(dir, ".core_user_1.dat.sync-orig"))
+	if err != nil {
+		t.Fatalf("preserved original removed: %v", err)
+	}
+	if string(preserved) != "user 1" {
+		t.Errorf("preserved original = %q, want %q", preserved, "user 1")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(matches) > 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
@@ -31,6 +31,22 @@ func ValidateOperation(profilePath, sourceUserFile, sourceCharFile string) error
 	return nil
 }
 
+// ValidateTargets validates the operation for every target profile, so that no
+// profile is written before all of them are known to be usable
+func ValidateTargets(profilePaths []string, sourceUserFile, sourceCharFile string) error {
+	if len(profilePaths) == 0 {
+		return fmt.Errorf("no target profiles selected")
+	}
+
+	for _, profilePath := range profilePaths {
+		if err := ValidateOperation(profilePath, sourceUserFile, sourceCharFile); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(profilePath), err)
+		}
+	}
+
+	return nil
+}
+
 // checkWritable checks if a directory is writable
 func checkWritable(dirPath string) error {
 	testFile := filepath.Join(dirPath, ".write_test")